@@ -1,29 +1,101 @@
 package main
 
 import (
+	"context"
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"gohighlevel/pkg/db"
+	"gohighlevel/pkg/grpcserver"
+	"gohighlevel/pkg/grpcserver/ingestionpb"
+	"gohighlevel/pkg/interfaces"
 	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/server"
 	"gohighlevel/pkg/service"
+	"gohighlevel/pkg/spool"
 	"gohighlevel/pkg/validator"
+
+	"google.golang.org/grpc"
+)
+
+// Command line flags selecting the run mode
+var (
+	input        = flag.String("input", "samples.json", "file to process once, or - to read standard input")
+	inputFormat  = flag.String("format", "", "input format (json, ndjson, csv or tsv); detected from the file extension if empty")
+	pullURL      = flag.String("pull", "", "URL to pull pages of samples from instead of reading -input")
+	fieldMapping = flag.String("field-mapping", "", "JSON file mapping input record fields onto sample fields")
+	rulesFile    = flag.String("rules", "", "YAML or JSON file of validation rules applied in addition to the built-in checks")
+	maxSkew      = flag.Duration("max-future-skew", 24*time.Hour, "how far in the future createdAt may be; 0 for no limit")
+	maxAge       = flag.Duration("max-age", 0, "retention horizon: how old createdAt may be, e.g. 8760h; 0 for no limit")
+	flagBounds   = flag.Bool("flag-out-of-bounds", false, "accept samples outside -max-future-skew or -max-age with a warning instead of rejecting them")
+	domainPolicy = flag.String("domain-policy", "", "YAML or JSON file of email domain blocklists, per-customer allowlists and disposable-provider settings")
+	profilesFile = flag.String("profiles", "", "YAML or JSON file of validation profiles and the customers assigned to them")
+	emailLevel   = flag.String("email-strictness", "standard", "which email addresses are accepted: strict (ASCII local parts), standard or lax (quoted local parts and domain literals)")
+	suggestTypos = flag.Bool("suggest-domains", true, "suggest corrections for misspelt email domains such as gmial.com, and flag samples using them")
+	knownDomains = flag.String("known-domains", "", "file of extra known email domains, one per line, to suggest corrections against")
+	autoCorrect  = flag.Bool("auto-correct-email", false, "replace misspelt email domains with the suggested ones, keeping the original on the document")
+	timeZone     = flag.String("time-zone", "UTC", "time zone for timestamps without an offset, e.g. Asia/Kolkata")
+	epochTimes   = flag.Bool("epoch-timestamps", false, "accept numeric timestamps as epoch seconds or milliseconds")
+	timeLayouts  stringList
+	trimSpace    = flag.Bool("trim", true, "trim surrounding whitespace from every input field")
+	lowerDomain  = flag.Bool("lowercase-email-domain", true, "lowercase the domain of email addresses")
+	nfcNames     = flag.Bool("nfc-names", true, "apply Unicode NFC normalization to names")
+	collapseWS   = flag.Bool("collapse-whitespace", true, "collapse runs of whitespace in names to a single space")
+	allowAttrs   stringList
+	denyAttrs    stringList
+	rateLimit    = flag.Int("rate-limit", 5, "requests allowed per customer per minute")
+	rateStrategy = flag.String("rate-limit-strategy", ratelimiter.StrategySlidingLog, "rate limiting strategy: sliding-log or token-bucket")
+	rateBurst    = flag.Int("rate-burst", 0, "requests a customer may make at once with the token-bucket strategy; defaults to -rate-limit")
+	rateWindows  stringList
+	rateShared   = flag.Bool("rate-limit-shared", false, "share rate limits with other workers through fixed-window counters in MongoDB instead of -rate-limit-strategy")
+	rateMaxKeys  = flag.Int("rate-limit-max-keys", 100000, "most customers the rate limiter tracks at once; idle and then least recently active customers are forgotten beyond it; 0 for no limit")
+	rateTiers    = flag.String("rate-limit-tiers", "", "YAML or JSON file of rate limit tiers and the customers assigned to them; its default replaces -rate-limit")
+	rateMongo    = flag.Bool("rate-limits-from-mongo", false, "read per-customer rate limit tiers and overrides from the rateLimits collection")
+	watchDir     = flag.String("watch", "", "inbox directory to watch for sample files instead of processing samples.json once")
+	pollInterval = flag.Duration("poll-interval", 5*time.Second, "how often the inbox directory or -pull URL is polled for new samples")
+	staleLock    = flag.Duration("stale-lock-timeout", spool.DefaultStaleLockTimeout, "how long an inbox file lock may go unrefreshed before another worker takes it over")
+	httpAddr     = flag.String("http", "", "address to serve the HTTP ingestion API on, e.g. :8080")
+	grpcAddr     = flag.String("grpc", "", "address to serve the gRPC ingestion service on, e.g. :9090")
+	streamLimit  = flag.Int("grpc-max-stream-samples", grpcserver.DefaultMaxStreamSamples, "samples an IngestSamples stream may carry before it fails")
+	metricsAddr  = flag.String("metrics", "", "address to serve expvar metrics on at /debug/vars, e.g. :6060")
 )
 
-// rateLimit defines the maximum number of requests allowed per customer per minute
-const rateLimit = 5
+func init() {
+	flag.Var(&timeLayouts, "time-layout", "accepted timestamp layout in Go reference time notation, or common for a broad set of usual layouts; repeat for several (default RFC 3339)")
+	flag.Var(&allowAttrs, "attribute-allow", "extra input field to store as an attribute; repeat for several (default all)")
+	flag.Var(&denyAttrs, "attribute-deny", "extra input field to drop instead of storing it as an attribute; repeat for several")
+	flag.Var(&rateWindows, "rate-limit-window", "extra limit enforced with -rate-limit, as requests/window, e.g. 200/1h; repeat for several")
+}
+
+// stringList is a flag that can be repeated to collect several values.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
 
 // main is the entry point of the application. It:
 // 1. Sets up the error logging
 // 2. Initializes the MongoDB connection
 // 3. Creates validator, rate limiter, and sample service instances
-// 4. Processes the samples from samples.json
+// 4. Processes the samples from -input or -pull (or serves -watch/-http/-grpc)
 // 5. Reports the processing results
 func main() {
-	// Remove error.log file if it exists to start fresh
-	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
-		log.Printf("Warning: Failed to remove old error.log: %v\n", err)
+	flag.Parse()
+
+	// Remove error.log and warnings.log files if they exist to start fresh
+	for _, name := range []string{"error.log", "warnings.log"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove old %s: %v\n", name, err)
+		}
 	}
 
 	// Initialize MongoDB connection
@@ -33,13 +105,136 @@ func main() {
 	}
 	defer mongoDB.Close()
 
+	// Load the mapping for upstream schemas other than the native one
+	var mapping service.FieldMapping
+	if *fieldMapping != "" {
+		var err error
+		if mapping, err = service.LoadFieldMapping(*fieldMapping); err != nil {
+			log.Fatalf("Failed to load field mapping: %v", err)
+		}
+	}
+
+	// Configure the accepted timestamp formats
+	loc, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		log.Fatalf("Invalid time zone: %v", err)
+	}
+	timeParser := service.TimeParser{Layouts: service.ExpandTimeLayouts(timeLayouts), Location: loc, ParseEpoch: *epochTimes}
+
+	// Compile the declarative validation rules
+	var rules *validator.RuleSet
+	if *rulesFile != "" {
+		if rules, err = validator.LoadRules(*rulesFile); err != nil {
+			log.Fatalf("Failed to load validation rules: %v", err)
+		}
+	}
+
+	strictness, err := validator.ParseEmailStrictness(*emailLevel)
+	if err != nil {
+		log.Fatalf("Invalid email strictness: %v", err)
+	}
+
+	// Load the email domain policy
+	var domains *validator.DomainPolicy
+	if *domainPolicy != "" {
+		if domains, err = validator.LoadDomainPolicy(*domainPolicy); err != nil {
+			log.Fatalf("Failed to load domain policy: %v", err)
+		}
+	}
+
+	// Load the known domains misspelt email domains are corrected to
+	var suggester *validator.DomainSuggester
+	switch {
+	case *knownDomains != "":
+		if suggester, err = validator.LoadDomainSuggester(*knownDomains); err != nil {
+			log.Fatalf("Failed to load known domains: %v", err)
+		}
+	case *suggestTypos:
+		suggester = validator.NewDomainSuggester()
+	}
+
+	validatorOpts := []validator.Option{
+		validator.WithRules(rules),
+		validator.WithEmailStrictness(strictness),
+		validator.WithDomainPolicy(domains),
+		validator.WithDomainSuggester(suggester),
+		validator.WithEmailAutoCorrect(*autoCorrect),
+		validator.WithTimeBounds(validator.TimeBounds{MaxFutureSkew: *maxSkew, MaxAge: *maxAge, Flag: *flagBounds}),
+	}
+
+	// Load the validation profiles of individual customers
+	var profiles service.ValidationProfiles
+	if *profilesFile != "" {
+		loaded, err := validator.LoadProfiles(*profilesFile, mongoDB, validatorOpts...)
+		if err != nil {
+			log.Fatalf("Failed to load validation profiles: %v", err)
+		}
+		profiles = validationProfiles(loaded)
+	}
+
 	// Initialize components with their dependencies
-	v := validator.NewValidator(mongoDB)                     // Validator for sample data
-	r := ratelimiter.NewRateLimiter(rateLimit)               // Rate limiter to prevent too many requests, in this case 5 requests per customer per minute
-	sampleService := service.NewSampleService(v, r, mongoDB) // Service to process samples
+	v := validator.NewValidator(mongoDB, validatorOpts...) // Validator for sample data
+
+	// Rate limiter to prevent too many requests per customer
+	tiers, err := rateLimitTiers(mongoDB)
+	if err != nil {
+		log.Fatalf("Failed to load rate limit tiers: %v", err)
+	}
+	var r interfaces.RateLimiter
+	if *rateShared {
+		if err := checkSharedRateLimitFlags(); err != nil {
+			log.Fatalf("Invalid rate limit flags: %v", err)
+		}
+		r, err = db.NewMongoRateLimiter(mongoDB, tiers)
+	} else {
+		r, err = ratelimiter.Config{Strategy: *rateStrategy, Tiers: tiers, MaxKeys: *rateMaxKeys}.New()
+	}
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	if t, ok := r.(interfaces.TrackingRateLimiter); ok {
+		expvar.Publish("ratelimiter_tracked_keys", expvar.Func(func() any { return t.TrackedKeys() }))
+	}
+	if *metricsAddr != "" {
+		go serveMetrics()
+	}
+
+	sampleService := service.NewSampleService(v, r, mongoDB,
+		service.WithFieldMapping(mapping),
+		service.WithTimeParser(timeParser),
+		service.WithNormalization(service.Normalization{
+			TrimSpace:            *trimSpace,
+			LowercaseEmailDomain: *lowerDomain,
+			NFCNames:             *nfcNames,
+			CollapseWhitespace:   *collapseWS,
+		}),
+		service.WithAttributePolicy(service.AttributePolicy{Allow: allowAttrs, Deny: denyAttrs}),
+		service.WithValidationProfiles(profiles)) // Service to process samples
+
+	if *watchDir != "" {
+		runWatcher(sampleService)
+		return
+	}
+	if *httpAddr != "" {
+		runHTTPServer(sampleService)
+		return
+	}
+	if *grpcAddr != "" {
+		runGRPCServer(sampleService)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Process all samples from the input
+	src, err := openSource(ctx, service.DecodeOptions{FieldMapping: mapping})
+	if err != nil {
+		log.Fatalf("Failed to open input: %v", err)
+	}
+	defer src.Close()
 
-	// Process all samples from the JSON file
-	result, err := sampleService.ProcessSamplesFile("samples.json")
+	result, err := sampleService.ProcessSource(src)
 	if err != nil {
 		log.Fatalf("Failed to process samples: %v", err)
 	}
@@ -48,4 +243,167 @@ func main() {
 	fmt.Printf("Total samples: %d\n", result.SuccessCount+result.ErrorCount)
 	fmt.Printf("Successfully processed %d samples\n", result.SuccessCount)
 	fmt.Printf("Failed to process %d samples\n", result.ErrorCount)
+	fmt.Printf("Processed %d samples with warnings\n", result.WarningCount)
+}
+
+// validationProfiles turns the loaded profiles into single-validator
+// chains for the sample service.
+func validationProfiles(p *validator.Profiles) service.ValidationProfiles {
+	chains := make(map[string][]interfaces.Validator, len(p.Validators))
+	for name, v := range p.Validators {
+		chains[name] = []interfaces.Validator{v}
+	}
+	return service.ValidationProfiles{Chains: chains, Customers: p.Customers}
+}
+
+// rateLimitTiers builds the rate limits of each customer from -rate-limit,
+// -rate-burst and -rate-limit-window, the -rate-limit-tiers file and, with
+// -rate-limits-from-mongo, the overrides stored in MongoDB, which take
+// precedence over the file's.
+func rateLimitTiers(mongoDB *db.MongoDatabase) (*ratelimiter.Tiers, error) {
+	var config ratelimiter.TierConfig
+	if *rateTiers != "" {
+		var err error
+		if config, err = ratelimiter.LoadTierConfig(*rateTiers); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.Default) == 0 {
+		config.Default = []ratelimiter.Limit{{Requests: *rateLimit, Burst: *rateBurst}}
+		for _, w := range rateWindows {
+			l, err := ratelimiter.ParseLimit(w)
+			if err != nil {
+				return nil, err
+			}
+			config.Default = append(config.Default, l)
+		}
+	}
+
+	if *rateMongo {
+		overrides, err := mongoDB.RateLimitOverrides()
+		if err != nil {
+			return nil, err
+		}
+		if config.Customers == nil {
+			config.Customers = make(map[string]ratelimiter.CustomerLimit, len(overrides))
+		}
+		for customerID, l := range overrides {
+			config.Customers[customerID] = l
+		}
+	}
+	return ratelimiter.NewTiers(config)
+}
+
+// checkSharedRateLimitFlags rejects the in-memory rate limiter flags that
+// -rate-limit-shared does not support, rather than silently ignoring them.
+func checkSharedRateLimitFlags() error {
+	var unsupported []string
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rate-burst", "rate-limit-max-keys":
+			unsupported = append(unsupported, "-"+f.Name)
+		case "rate-limit-strategy":
+			if f.Value.String() != ratelimiter.StrategySlidingLog {
+				unsupported = append(unsupported, "-rate-limit-strategy "+f.Value.String())
+			}
+		}
+	})
+	if len(unsupported) > 0 {
+		return fmt.Errorf("-rate-limit-shared counts requests in fixed windows and does not support %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// openSource opens the source selected by the -input, -format and -pull
+// flags. A pull source keeps polling until ctx is cancelled.
+func openSource(ctx context.Context, opts service.DecodeOptions) (service.Source, error) {
+	if *pullURL != "" {
+		return service.NewHTTPSource(ctx, service.HTTPSourceConfig{
+			URL:          *pullURL,
+			PollInterval: *pollInterval,
+		}, opts), nil
+	}
+
+	opts.Format = service.Format(*inputFormat)
+	if *input == "-" {
+		return service.NewStdinSource(opts)
+	}
+	return service.NewFileSource(*input, opts)
+}
+
+// serveMetrics serves the published expvar variables, such as the number
+// of customers the rate limiter tracks, on -metrics.
+func serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	log.Printf("Serving metrics on %s\n", *metricsAddr)
+	if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+		log.Fatalf("Metrics server failed: %v", err)
+	}
+}
+
+// runWatcher ingests files dropped into the watch directory until the
+// process is interrupted.
+func runWatcher(sampleService *service.SampleService) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Watching %s for sample files\n", *watchDir)
+	w := spool.NewWatcher(*watchDir, sampleService, *pollInterval, spool.WithStaleLockTimeout(*staleLock))
+	if err := w.Run(ctx); err != nil {
+		log.Fatalf("Failed to watch %s: %v", *watchDir, err)
+	}
+}
+
+// runHTTPServer serves the HTTP ingestion API until the process is
+// interrupted, then lets in-flight requests finish.
+func runHTTPServer(sampleService *service.SampleService) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:              *httpAddr,
+		Handler:           server.NewServer(sampleService),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	idle := make(chan struct{})
+	go func() {
+		defer close(idle)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down HTTP server: %v\n", err)
+		}
+	}()
+
+	log.Printf("Serving HTTP ingestion API on %s\n", *httpAddr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("HTTP server failed: %v", err)
+	}
+	<-idle
+}
+
+// runGRPCServer serves the gRPC ingestion service until the process is
+// interrupted, then lets in-flight calls finish.
+func runGRPCServer(sampleService *service.SampleService) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lis, err := net.Listen("tcp", *grpcAddr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *grpcAddr, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	ingestionpb.RegisterIngestionServiceServer(grpcServer, grpcserver.NewServer(sampleService, grpcserver.WithMaxStreamSamples(*streamLimit)))
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	log.Printf("Serving gRPC ingestion service on %s\n", *grpcAddr)
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatalf("gRPC server failed: %v", err)
+	}
 }
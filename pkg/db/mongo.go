@@ -11,6 +11,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// databaseName is the MongoDB database the worker's collections live in.
+const databaseName = "gohighlevel"
+
 type MongoDatabase struct {
 	client     *mongo.Client
 	collection *mongo.Collection
@@ -38,7 +41,7 @@ func (m *MongoDatabase) Init() error {
 		return err
 	}
 
-	m.collection = m.client.Database("gohighlevel").Collection("samples")
+	m.collection = m.client.Database(databaseName).Collection("samples")
 	log.Println("Connected to MongoDB!")
 	return nil
 }
@@ -59,19 +62,65 @@ func (m *MongoDatabase) InsertSample(sample types.Sample) error {
 	defer cancel()
 
 	doc := struct {
-		CustomerID string    `bson:"customerId"`
-		Name       string    `bson:"name"`
-		Email      string    `bson:"email"`
-		CreatedAt  time.Time `bson:"createdAt"`
-		IngestedAt time.Time `bson:"ingestedAt"`
+		CustomerID string                 `bson:"customerId"`
+		Name       string                 `bson:"name"`
+		Email      string                 `bson:"email"`
+		CreatedAt  time.Time              `bson:"createdAt"`
+		UpdatedAt  time.Time              `bson:"updatedAt,omitempty"`
+		Attributes map[string]interface{} `bson:"attributes,omitempty"`
+		Warnings   []warning              `bson:"warnings,omitempty"`
+		IngestedAt time.Time              `bson:"ingestedAt"`
+
+		Corrections []correction `bson:"corrections,omitempty"`
 	}{
 		CustomerID: sample.CustomerID,
 		Name:       sample.Name,
 		Email:      sample.Email,
 		CreatedAt:  sample.CreatedAt,
+		UpdatedAt:  sample.UpdatedAt,
+		Attributes: sample.Attributes,
+		Warnings:   warnings(sample.Warnings),
 		IngestedAt: time.Now(),
+
+		Corrections: corrections(sample.Corrections),
 	}
 
 	_, err := m.collection.InsertOne(ctx, doc)
 	return err
 }
+
+// warning is a failed check stored with a sample that was accepted anyway.
+type warning struct {
+	Code   string `bson:"code"`
+	Field  string `bson:"field"`
+	Rule   string `bson:"rule,omitempty"`
+	Reason string `bson:"reason"`
+
+	Suggestion string `bson:"suggestion,omitempty"`
+}
+
+// warnings converts validation errors to their stored form.
+func warnings(errs types.ValidationErrors) []warning {
+	var ws []warning
+	for _, err := range errs {
+		ws = append(ws, warning{Code: err.Code, Field: err.Field, Rule: err.Rule, Reason: err.Reason, Suggestion: err.Suggestion})
+	}
+	return ws
+}
+
+// correction is an automatically corrected value, stored with both the
+// original and the corrected value.
+type correction struct {
+	Field     string `bson:"field"`
+	Original  string `bson:"original"`
+	Corrected string `bson:"corrected"`
+}
+
+// corrections converts the corrections of a sample to their stored form.
+func corrections(cs []types.Correction) []correction {
+	var stored []correction
+	for _, c := range cs {
+		stored = append(stored, correction{Field: c.Field, Original: c.Original, Corrected: c.Corrected})
+	}
+	return stored
+}
@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"gohighlevel/pkg/ratelimiter"
 	"gohighlevel/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func setupTestDB(tb testing.TB) (*MongoDatabase, func()) {
@@ -67,6 +70,104 @@ func TestMongoOperations(t *testing.T) {
 	}
 }
 
+func TestRateLimitOverrides(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	collection := db.client.Database(databaseName).Collection(rateLimitsCollection)
+	defer collection.Drop(ctx)
+	_, err := collection.InsertMany(ctx, []interface{}{
+		rateLimit{CustomerID: "client-A", Tier: "pro"},
+		rateLimit{CustomerID: "client-B", Limits: []limit{{Requests: 1000, Window: "1h"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert rate limits: %v", err)
+	}
+
+	overrides, err := db.RateLimitOverrides()
+	if err != nil {
+		t.Fatalf("RateLimitOverrides() error = %v", err)
+	}
+	if got := overrides["client-A"]; got.Tier != "pro" {
+		t.Errorf("client-A: got %+v, want tier pro", got)
+	}
+	if got := overrides["client-B"]; len(got.Limits) != 1 || got.Limits[0].Requests != 1000 || got.Limits[0].Window != "1h" {
+		t.Errorf("client-B: got %+v, want 1000 requests per 1h", got)
+	}
+}
+
+func TestMongoRateLimiter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tiers, err := ratelimiter.NewTiers(ratelimiter.TierConfig{
+		Default: []ratelimiter.Limit{{Requests: 2}, {Requests: 3, Window: "1h"}},
+	})
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+	limiter, err := NewMongoRateLimiter(db, tiers)
+	if err != nil {
+		t.Fatalf("NewMongoRateLimiter() error = %v", err)
+	}
+	defer limiter.counters.Drop(context.Background())
+
+	// Two limiters on the same database stand in for two workers
+	other, err := NewMongoRateLimiter(db, tiers)
+	if err != nil {
+		t.Fatalf("NewMongoRateLimiter() error = %v", err)
+	}
+	customerID := fmt.Sprintf("shared-%d", time.Now().UnixNano())
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Test 1: The minute limit is shared between the workers
+	if allowed, remaining := limiter.Allow(customerID, baseTime); !allowed || remaining != 1 {
+		t.Fatalf("Allow() = %v, %d, want the first request allowed with 1 remaining", allowed, remaining)
+	}
+	if allowed, remaining := other.Allow(customerID, baseTime.Add(time.Second)); !allowed || remaining != 0 {
+		t.Fatalf("Allow() = %v, %d, want the second request allowed with 0 remaining", allowed, remaining)
+	}
+	if limiter.IsAllowed(customerID, baseTime.Add(2*time.Second)) {
+		t.Error("3rd request within the minute should be denied")
+	}
+	if requests, window := other.ExceededLimit(customerID, baseTime.Add(2*time.Second)); requests != 2 || window != time.Minute {
+		t.Errorf("ExceededLimit() = %d per %v, want 2 per 1m", requests, window)
+	}
+	if wait := other.RetryAfter(customerID, baseTime.Add(2*time.Second)); wait != 58*time.Second {
+		t.Errorf("Expected to wait for the next minute, got %v", wait)
+	}
+
+	// Test 2: The rejected request was not counted against the hour
+	if !other.IsAllowed(customerID, baseTime.Add(time.Minute)) {
+		t.Error("Request in the next minute should be allowed")
+	}
+	if limiter.IsAllowed(customerID, baseTime.Add(2*time.Minute)) {
+		t.Error("4th request within the hour should be denied")
+	}
+	if requests, window := limiter.ExceededLimit(customerID, baseTime.Add(2*time.Minute)); requests != 3 || window != time.Hour {
+		t.Errorf("ExceededLimit() = %d per %v, want 3 per 1h", requests, window)
+	}
+
+	// Test 3: Counters expire a grace period after their event-time window
+	// ends, or after their last use for windows that already ended
+	now := time.Now()
+	limiter.IsAllowed(customerID, now)
+	hour := ratelimiter.Window{Requests: 3, Duration: time.Hour}
+	for id, want := range map[counterID]time.Time{
+		newCounterID(customerID, hour, now):      newCounterID(customerID, hour, now).Start.Add(time.Hour + counterGracePeriod),
+		newCounterID(customerID, hour, baseTime): now.Add(counterGracePeriod),
+	} {
+		var c counter
+		if err := limiter.counters.FindOne(context.Background(), bson.M{"_id": id}).Decode(&c); err != nil {
+			t.Fatalf("Failed to read counter: %v", err)
+		}
+		if c.ExpiresAt.Before(want.Add(-time.Minute)) || c.ExpiresAt.After(want.Add(time.Minute)) {
+			t.Errorf("Counter of %v expires at %v, want about %v", id.Start, c.ExpiresAt, want)
+		}
+	}
+}
+
 func BenchmarkInsertSample(b *testing.B) {
 	db, cleanup := setupTestDB(b)
 	defer cleanup()
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gohighlevel/pkg/ratelimiter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rateCountersCollection holds the request counters of MongoRateLimiter.
+const rateCountersCollection = "rateCounters"
+
+// counterGracePeriod is how long a counter is kept after both its window
+// has ended and it was last used, so that samples arriving late for a
+// window are still counted in it.
+const counterGracePeriod = time.Hour
+
+// MongoRateLimiter is a rate limiter whose counters live in MongoDB, so that
+// every worker using the same database shares the limits. Each limit of a
+// customer counts requests in fixed windows aligned to multiples of its
+// duration, one document per window, incremented with atomic upserts. A
+// TTL index removes a window's document a grace period after the window
+// has ended, or after its last use if later. Unlike the in-memory
+// limiters, fixed windows allow up to twice a limit across a window
+// boundary.
+//
+// If MongoDB cannot be reached, requests are allowed and the error logged,
+// so that an outage of the counters does not stop ingestion.
+type MongoRateLimiter struct {
+	counters *mongo.Collection
+	tiers    *ratelimiter.Tiers
+}
+
+// counterID identifies the counter of one window of a customer's limit.
+type counterID struct {
+	CustomerID string        `bson:"customerId"`
+	Window     time.Duration `bson:"window"`
+	Start      time.Time     `bson:"start"`
+}
+
+// counter is a document of the rate counters collection.
+type counter struct {
+	ID        counterID `bson:"_id"`
+	Count     int       `bson:"count"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// NewMongoRateLimiter creates a rate limiter applying the limits the tiers
+// resolve for each customer, counting requests in the rateCounters
+// collection of m. Limits with a burst are rejected, as fixed windows cannot
+// honour them.
+func NewMongoRateLimiter(m *MongoDatabase, tiers *ratelimiter.Tiers) (*MongoRateLimiter, error) {
+	if tiers.HasBurst() {
+		return nil, fmt.Errorf("shared rate limits do not support bursts")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	counters := m.client.Database(databaseName).Collection(rateCountersCollection)
+	_, err := counters.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating rate counter index: %v", err)
+	}
+	return &MongoRateLimiter{counters: counters, tiers: tiers}, nil
+}
+
+// IsAllowed counts the request in the current window of each of the
+// customer's limits, and takes it back out if any of them is exceeded.
+func (r *MongoRateLimiter) IsAllowed(customerID string, createdAt time.Time) bool {
+	allowed, _ := r.Allow(customerID, createdAt)
+	return allowed
+}
+
+// Allow counts the request like IsAllowed, and returns the number of
+// requests the customer has left in the window of createdAt of their most
+// used limit. Windows it could not count the request in are taken to hold
+// only this request.
+func (r *MongoRateLimiter) Allow(customerID string, createdAt time.Time) (allowed bool, remaining int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	remaining = math.MaxInt
+	var counted []counterID
+	for _, w := range r.tiers.Windows(customerID) {
+		id := newCounterID(customerID, w, createdAt)
+		count, err := r.increment(ctx, id)
+		if err != nil {
+			log.Printf("Error counting request of %s, allowing it: %v\n", customerID, err)
+			return true, min(remaining, w.Requests-1)
+		}
+		counted = append(counted, id)
+		if count > w.Requests {
+			r.uncount(ctx, counted)
+			return false, 0
+		}
+		remaining = min(remaining, w.Requests-count)
+	}
+	return true, remaining
+}
+
+// GetRemainingRequests returns the number of requests the customer has
+// left in the current window of their most used limit.
+func (r *MongoRateLimiter) GetRemainingRequests(customerID string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var remaining int
+	for i, w := range r.tiers.Windows(customerID) {
+		count, err := r.count(ctx, newCounterID(customerID, w, now))
+		if err != nil {
+			log.Printf("Error reading request count of %s: %v\n", customerID, err)
+		}
+		if left := w.Requests - count; i == 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining
+}
+
+// RetryAfter returns how long after at the customer has to wait before a
+// request would be allowed again, or zero if a request at at is allowed.
+func (r *MongoRateLimiter) RetryAfter(customerID string, at time.Time) time.Duration {
+	_, wait := r.exceeded(customerID, at)
+	return wait
+}
+
+// ExceededLimit returns the limit, as a number of requests per window,
+// that keeps the customer waiting longest at at, or zeros if a request at
+// at is allowed.
+func (r *MongoRateLimiter) ExceededLimit(customerID string, at time.Time) (requests int, window time.Duration) {
+	w, _ := r.exceeded(customerID, at)
+	return w.Requests, w.Duration
+}
+
+// exceeded returns the customer's full window with the longest wait at at,
+// and the wait until it ends.
+func (r *MongoRateLimiter) exceeded(customerID string, at time.Time) (ratelimiter.Window, time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var longest ratelimiter.Window
+	var longestWait time.Duration
+	for _, w := range r.tiers.Windows(customerID) {
+		id := newCounterID(customerID, w, at)
+		count, err := r.count(ctx, id)
+		if err != nil {
+			log.Printf("Error reading request count of %s: %v\n", customerID, err)
+			continue
+		}
+		if wait := id.Start.Add(w.Duration).Sub(at); count >= w.Requests && wait > longestWait {
+			longest, longestWait = w, wait
+		}
+	}
+	return longest, longestWait
+}
+
+// increment adds one to a counter, creating it if needed, and returns the
+// new count. The counter expires a grace period after the end of its
+// window, which is measured in event time, or after this use if later, so
+// that replayed samples do not lose their counters mid-window.
+func (r *MongoRateLimiter) increment(ctx context.Context, id counterID) (int, error) {
+	expiresAt := id.Start.Add(id.Window)
+	if now := time.Now(); now.After(expiresAt) {
+		expiresAt = now
+	}
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$max": bson.M{"expiresAt": expiresAt.Add(counterGracePeriod)},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var c counter
+	err := r.counters.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&c)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another worker created the counter concurrently; it exists now
+		err = r.counters.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&c)
+	}
+	return c.Count, err
+}
+
+// uncount takes a rejected request back out of the counters it was
+// counted in.
+func (r *MongoRateLimiter) uncount(ctx context.Context, ids []counterID) {
+	for _, id := range ids {
+		if _, err := r.counters.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"count": -1}}); err != nil {
+			log.Printf("Error uncounting rejected request of %s: %v\n", id.CustomerID, err)
+		}
+	}
+}
+
+// count returns the value of a counter, zero if it does not exist.
+func (r *MongoRateLimiter) count(ctx context.Context, id counterID) (int, error) {
+	var c counter
+	err := r.counters.FindOne(ctx, bson.M{"_id": id}).Decode(&c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return c.Count, err
+}
+
+// newCounterID returns the counter of the window of w that at falls in.
+func newCounterID(customerID string, w ratelimiter.Window, at time.Time) counterID {
+	return counterID{CustomerID: customerID, Window: w.Duration, Start: at.Truncate(w.Duration).UTC()}
+}
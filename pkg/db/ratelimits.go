@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gohighlevel/pkg/ratelimiter"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// rateLimitsCollection holds the rate limit tiers and overrides of
+// individual customers.
+const rateLimitsCollection = "rateLimits"
+
+// rateLimit is a document of the rate limits collection, assigning a
+// customer either a tier or limits of their own.
+type rateLimit struct {
+	CustomerID string  `bson:"customerId"`
+	Tier       string  `bson:"tier,omitempty"`
+	Limits     []limit `bson:"limits,omitempty"`
+}
+
+// limit allows a number of requests per window, such as "1h".
+type limit struct {
+	Requests int    `bson:"requests"`
+	Window   string `bson:"window,omitempty"`
+	Burst    int    `bson:"burst,omitempty"`
+}
+
+// RateLimitOverrides reads the per-customer rate limits of the rateLimits
+// collection, documents of the form {customerId: "client-A", tier: "pro"}
+// or {customerId: "client-B", limits: [{requests: 1000, window: "1h"}]},
+// keyed by customer ID.
+func (m *MongoDatabase) RateLimitOverrides() (map[string]ratelimiter.CustomerLimit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := m.client.Database(databaseName).Collection(rateLimitsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading rate limits: %v", err)
+	}
+	var docs []rateLimit
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("error decoding rate limits: %v", err)
+	}
+
+	overrides := make(map[string]ratelimiter.CustomerLimit, len(docs))
+	for _, doc := range docs {
+		c := ratelimiter.CustomerLimit{Tier: doc.Tier}
+		for _, l := range doc.Limits {
+			c.Limits = append(c.Limits, ratelimiter.Limit{Requests: l.Requests, Window: l.Window, Burst: l.Burst})
+		}
+		overrides[doc.CustomerID] = c
+	}
+	return overrides, nil
+}
@@ -0,0 +1,5 @@
+// Package ingestionpb contains the protobuf messages and gRPC stubs of the
+// ingestion service, generated from ingestion.proto.
+package ingestionpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ingestion.proto
@@ -0,0 +1,171 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: ingestion.proto
+
+package ingestionpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	IngestionService_IngestSample_FullMethodName  = "/ingestion.v1.IngestionService/IngestSample"
+	IngestionService_IngestSamples_FullMethodName = "/ingestion.v1.IngestionService/IngestSamples"
+)
+
+// IngestionServiceClient is the client API for IngestionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// IngestionService runs samples through the same validation, rate limiting
+// and storage pipeline as file ingestion.
+type IngestionServiceClient interface {
+	// IngestSample ingests a single sample.
+	IngestSample(ctx context.Context, in *Sample, opts ...grpc.CallOption) (*IngestResult, error)
+	// IngestSamples ingests a stream of samples and reports on each of them
+	// once the client closes the stream. A stream may carry at most 10000
+	// samples by default (see -grpc-max-stream-samples); the excess sample
+	// fails the stream with RESOURCE_EXHAUSTED, after the samples before it
+	// have been ingested.
+	IngestSamples(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Sample, IngestSamplesResponse], error)
+}
+
+type ingestionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestionServiceClient(cc grpc.ClientConnInterface) IngestionServiceClient {
+	return &ingestionServiceClient{cc}
+}
+
+func (c *ingestionServiceClient) IngestSample(ctx context.Context, in *Sample, opts ...grpc.CallOption) (*IngestResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IngestResult)
+	err := c.cc.Invoke(ctx, IngestionService_IngestSample_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) IngestSamples(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Sample, IngestSamplesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IngestionService_ServiceDesc.Streams[0], IngestionService_IngestSamples_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Sample, IngestSamplesResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestionService_IngestSamplesClient = grpc.ClientStreamingClient[Sample, IngestSamplesResponse]
+
+// IngestionServiceServer is the server API for IngestionService service.
+// All implementations must embed UnimplementedIngestionServiceServer
+// for forward compatibility.
+//
+// IngestionService runs samples through the same validation, rate limiting
+// and storage pipeline as file ingestion.
+type IngestionServiceServer interface {
+	// IngestSample ingests a single sample.
+	IngestSample(context.Context, *Sample) (*IngestResult, error)
+	// IngestSamples ingests a stream of samples and reports on each of them
+	// once the client closes the stream. A stream may carry at most 10000
+	// samples by default (see -grpc-max-stream-samples); the excess sample
+	// fails the stream with RESOURCE_EXHAUSTED, after the samples before it
+	// have been ingested.
+	IngestSamples(grpc.ClientStreamingServer[Sample, IngestSamplesResponse]) error
+	mustEmbedUnimplementedIngestionServiceServer()
+}
+
+// UnimplementedIngestionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIngestionServiceServer struct{}
+
+func (UnimplementedIngestionServiceServer) IngestSample(context.Context, *Sample) (*IngestResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IngestSample not implemented")
+}
+func (UnimplementedIngestionServiceServer) IngestSamples(grpc.ClientStreamingServer[Sample, IngestSamplesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method IngestSamples not implemented")
+}
+func (UnimplementedIngestionServiceServer) mustEmbedUnimplementedIngestionServiceServer() {}
+func (UnimplementedIngestionServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeIngestionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestionServiceServer will
+// result in compilation errors.
+type UnsafeIngestionServiceServer interface {
+	mustEmbedUnimplementedIngestionServiceServer()
+}
+
+func RegisterIngestionServiceServer(s grpc.ServiceRegistrar, srv IngestionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedIngestionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IngestionService_ServiceDesc, srv)
+}
+
+func _IngestionService_IngestSample_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sample)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).IngestSample(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_IngestSample_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).IngestSample(ctx, req.(*Sample))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_IngestSamples_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IngestionServiceServer).IngestSamples(&grpc.GenericServerStream[Sample, IngestSamplesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IngestionService_IngestSamplesServer = grpc.ClientStreamingServer[Sample, IngestSamplesResponse]
+
+// IngestionService_ServiceDesc is the grpc.ServiceDesc for IngestionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IngestionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ingestion.v1.IngestionService",
+	HandlerType: (*IngestionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IngestSample",
+			Handler:    _IngestionService_IngestSample_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "IngestSamples",
+			Handler:       _IngestionService_IngestSamples_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingestion.proto",
+}
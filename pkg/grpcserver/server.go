@@ -0,0 +1,118 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"gohighlevel/pkg/grpcserver/ingestionpb"
+	"gohighlevel/pkg/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxStreamSamples is the number of samples an IngestSamples stream
+// may carry by default. The result of each is held until the stream ends.
+const DefaultMaxStreamSamples = 10000
+
+// Server implements the gRPC IngestionService on top of a SampleService,
+// so that samples received over gRPC go through the same validator, rate
+// limiter and database as every other input.
+type Server struct {
+	ingestionpb.UnimplementedIngestionServiceServer
+	service          *service.SampleService
+	maxStreamSamples int // Samples an IngestSamples stream may carry
+}
+
+// Option configures optional behaviour of a Server.
+type Option func(*Server)
+
+// WithMaxStreamSamples sets how many samples an IngestSamples stream may
+// carry before it fails with ResourceExhausted. The default is
+// DefaultMaxStreamSamples.
+func WithMaxStreamSamples(n int) Option {
+	return func(s *Server) {
+		s.maxStreamSamples = n
+	}
+}
+
+// NewServer creates a gRPC ingestion service backed by s.
+func NewServer(s *service.SampleService, opts ...Option) *Server {
+	srv := &Server{service: s, maxStreamSamples: DefaultMaxStreamSamples}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+// IngestSample ingests a single sample. Rejections are reported in the
+// result rather than as an RPC error.
+func (s *Server) IngestSample(ctx context.Context, sample *ingestionpb.Sample) (*ingestionpb.IngestResult, error) {
+	return s.ingest(0, sample), nil
+}
+
+// IngestSamples ingests samples as they arrive on the stream and replies
+// with the result of each one when the client closes the stream. As the
+// results are held until then, a stream carrying more than the maximum
+// number of samples fails with ResourceExhausted; the samples before the
+// excess one have been ingested.
+func (s *Server) IngestSamples(stream ingestionpb.IngestionService_IngestSamplesServer) error {
+	resp := &ingestionpb.IngestSamplesResponse{}
+	for index := int64(0); ; index++ {
+		sample, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		if index >= int64(s.maxStreamSamples) {
+			return status.Errorf(codes.ResourceExhausted, "stream exceeds %d samples, split it into several streams", s.maxStreamSamples)
+		}
+
+		result := s.ingest(index, sample)
+		if result.Status == ingestionpb.Status_STATUS_ACCEPTED {
+			resp.SuccessCount++
+		} else {
+			resp.ErrorCount++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+}
+
+// ingest processes one sample and describes the outcome.
+func (s *Server) ingest(index int64, sample *ingestionpb.Sample) *ingestionpb.IngestResult {
+	err := s.service.ProcessSample(service.CustomSample{
+		CustomerID: sample.GetCustomerId(),
+		Email:      sample.GetEmail(),
+		Name:       sample.GetName(),
+		CreatedAt:  sample.GetCreatedAt(),
+		UpdatedAt:  sample.GetUpdatedAt(),
+		Attributes: sample.GetAttributes().AsMap(),
+	})
+
+	result := &ingestionpb.IngestResult{
+		Index:      index,
+		CustomerId: sample.GetCustomerId(),
+		Status:     statusOf(err),
+	}
+	if err != nil {
+		result.Reason = service.Reason(err)
+	}
+	return result
+}
+
+// statusOf maps an error returned by ProcessSample to an ingestion status.
+func statusOf(err error) ingestionpb.Status {
+	switch {
+	case err == nil:
+		return ingestionpb.Status_STATUS_ACCEPTED
+	case errors.Is(err, service.ErrInvalidSample):
+		return ingestionpb.Status_STATUS_INVALID
+	case errors.Is(err, service.ErrRateLimited):
+		return ingestionpb.Status_STATUS_RATE_LIMITED
+	default:
+		return ingestionpb.Status_STATUS_STORAGE_ERROR
+	}
+}
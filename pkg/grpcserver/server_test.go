@@ -0,0 +1,141 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/grpcserver/ingestionpb"
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/service"
+	"gohighlevel/pkg/types"
+	"gohighlevel/pkg/validator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mockDB implements the Database interface for testing
+type mockDB struct{}
+
+func (m *mockDB) Init() error                            { return nil }
+func (m *mockDB) Close()                                 {}
+func (m *mockDB) InsertSample(sample types.Sample) error { return nil }
+
+// Helper function to start an in-process gRPC server and connect to it
+func setupTestClient(t *testing.T, opts ...Option) ingestionpb.IngestionServiceClient {
+	t.Cleanup(func() { os.Remove("error.log") })
+
+	db := &mockDB{}
+	s := service.NewSampleService(validator.NewValidator(db), ratelimiter.NewRateLimiter(5), db)
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	ingestionpb.RegisterIngestionServiceServer(grpcServer, NewServer(s, opts...))
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect to gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return ingestionpb.NewIngestionServiceClient(conn)
+}
+
+func newSample(customerID, email string) *ingestionpb.Sample {
+	return &ingestionpb.Sample{
+		CustomerId: customerID,
+		Email:      email,
+		Name:       "Test User",
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+}
+
+func TestIngestSample(t *testing.T) {
+	client := setupTestClient(t)
+
+	tests := []struct {
+		name       string
+		sample     *ingestionpb.Sample
+		wantStatus ingestionpb.Status
+		wantReason string
+	}{
+		{"valid sample", newSample("1", "test@example.com"), ingestionpb.Status_STATUS_ACCEPTED, ""},
+		{"invalid email", newSample("2", "not-an-email"), ingestionpb.Status_STATUS_INVALID, "invalid email format: missing @"},
+		{"missing customer", newSample("", "test@example.com"), ingestionpb.Status_STATUS_INVALID, "customer_id is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.IngestSample(context.Background(), tt.sample)
+			if err != nil {
+				t.Fatalf("IngestSample() error = %v", err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Expected status %v, got %v", tt.wantStatus, result.Status)
+			}
+			if result.Reason != tt.wantReason {
+				t.Errorf("Expected reason %q, got %q", tt.wantReason, result.Reason)
+			}
+		})
+	}
+}
+
+func TestIngestSamplesStream(t *testing.T) {
+	client := setupTestClient(t)
+
+	stream, err := client.IngestSamples(context.Background())
+	if err != nil {
+		t.Fatalf("IngestSamples() error = %v", err)
+	}
+
+	// 7 samples for the same customer: 5 accepted, 1 invalid, 1 rate limited
+	for i := 0; i < 5; i++ {
+		stream.Send(newSample("stream-test", "stream@example.com"))
+	}
+	stream.Send(newSample("stream-test", "bad-email"))
+	stream.Send(newSample("stream-test", "stream@example.com"))
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv() error = %v", err)
+	}
+	if resp.SuccessCount != 5 || resp.ErrorCount != 2 {
+		t.Errorf("Expected 5 successes and 2 errors, got %d and %d", resp.SuccessCount, resp.ErrorCount)
+	}
+	if len(resp.Results) != 7 {
+		t.Fatalf("Expected 7 results, got %d", len(resp.Results))
+	}
+	if got := resp.Results[5].Status; got != ingestionpb.Status_STATUS_INVALID {
+		t.Errorf("Expected result 5 to be invalid, got %v", got)
+	}
+	if got := resp.Results[6]; got.Status != ingestionpb.Status_STATUS_RATE_LIMITED || got.Reason != "rate limit exceeded: 5 per 1m" || got.Index != 6 {
+		t.Errorf("Expected result 6 to be rate limited, got %v", got)
+	}
+}
+
+func TestIngestSamplesStreamLimit(t *testing.T) {
+	client := setupTestClient(t, WithMaxStreamSamples(2))
+
+	stream, err := client.IngestSamples(context.Background())
+	if err != nil {
+		t.Fatalf("IngestSamples() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		stream.Send(newSample("limit-test", "limit@example.com"))
+	}
+
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("CloseAndRecv() error = %v, want ResourceExhausted", err)
+	}
+}
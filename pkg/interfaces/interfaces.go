@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"time"
+
 	"gohighlevel/pkg/types"
 )
 
@@ -10,6 +12,18 @@ type Validator interface {
 	WriteErrorLog(customerId, reason string) error
 }
 
+// WarningValidator is a Validator that can accept a sample with warnings
+type WarningValidator interface {
+	Validator
+	Validate(sample types.Sample) (types.ValidationErrors, error)
+}
+
+// Corrector is implemented by validators that fix common mistakes in a
+// sample before it is validated
+type Corrector interface {
+	CorrectSample(sample types.Sample) types.Sample
+}
+
 // Database interface for database operations
 type Database interface {
 	Init() error
@@ -17,8 +31,31 @@ type Database interface {
 	InsertSample(sample types.Sample) error
 }
 
-// RateLimiter interface for rate limiting
+// RateLimiter interface for rate limiting, keyed by customer ID and
+// driven by the event time of each sample
 type RateLimiter interface {
-	IsAllowed(customerID string) bool
+	IsAllowed(customerID string, at time.Time) bool
 	GetRemainingRequests(customerID string) int
+	RetryAfter(customerID string, at time.Time) time.Duration
+}
+
+// AtomicRateLimiter is a RateLimiter that can check a request and report
+// the customer's remaining requests at its event time in a single step
+type AtomicRateLimiter interface {
+	RateLimiter
+	Allow(customerID string, at time.Time) (allowed bool, remaining int)
+}
+
+// WindowedRateLimiter is a RateLimiter that can report which of a
+// customer's limits a rejected request exceeded
+type WindowedRateLimiter interface {
+	RateLimiter
+	ExceededLimit(customerID string, at time.Time) (requests int, window time.Duration)
+}
+
+// TrackingRateLimiter is a RateLimiter that keeps per-customer state in
+// memory and can report for how many customers
+type TrackingRateLimiter interface {
+	RateLimiter
+	TrackedKeys() int
 }
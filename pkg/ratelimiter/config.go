@@ -0,0 +1,51 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"time"
+
+	"gohighlevel/pkg/interfaces"
+)
+
+// Rate limiting strategies
+const (
+	StrategySlidingLog  = "sliding-log"  // RateLimiter: exact, but keeps every request of the last minute
+	StrategyTokenBucket = "token-bucket" // TokenBucket: constant state per customer, with a burst allowance
+)
+
+// Config selects and configures a rate limiting strategy.
+type Config struct {
+	Strategy          string `json:"strategy" yaml:"strategy"`                   // sliding-log (default) or token-bucket
+	RequestsPerMinute int    `json:"requestsPerMinute" yaml:"requestsPerMinute"` // Steady rate allowed per customer
+	Burst             int    `json:"burst" yaml:"burst"`                         // Token bucket size; defaults to RequestsPerMinute
+
+	// Tiers, if set, resolves the limit of each customer instead of
+	// RequestsPerMinute and Burst.
+	Tiers *Tiers `json:"-" yaml:"-"`
+
+	// MaxKeys caps the number of customers tracked, see WithMaxKeys.
+	MaxKeys int `json:"maxKeys" yaml:"maxKeys"`
+}
+
+// New creates a rate limiter of the configured strategy. Bursts are
+// rejected with the sliding-log strategy, which cannot honour them.
+func (c Config) New() (interfaces.RateLimiter, error) {
+	tiers := c.Tiers
+	if tiers == nil {
+		if c.RequestsPerMinute < 1 {
+			return nil, fmt.Errorf("requests per minute must be positive, got %d", c.RequestsPerMinute)
+		}
+		tiers = uniformTiers(c.RequestsPerMinute, time.Minute, c.Burst)
+	}
+	switch c.Strategy {
+	case "", StrategySlidingLog:
+		if tiers.HasBurst() {
+			return nil, fmt.Errorf("the %s strategy does not support bursts, use %s", StrategySlidingLog, StrategyTokenBucket)
+		}
+		return NewTieredRateLimiter(tiers, WithMaxKeys(c.MaxKeys)), nil
+	case StrategyTokenBucket:
+		return NewTieredTokenBucket(tiers, WithMaxKeys(c.MaxKeys)), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limiting strategy %q", c.Strategy)
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimiter
+
+import (
+	"slices"
+	"time"
+)
+
+// minSweepInterval is the least number of requests between two sweeps of
+// idle keys. Between sweeps a limiter sees at least as many requests as it
+// tracks keys, so that sweeping costs O(1) per request.
+const minSweepInterval = 1024
+
+// lowWaterFraction is the fraction of the cap a limiter at the cap evicts
+// down to, so that the O(n log n) cost of finding the least recently active
+// keys is paid once for every tenth of the cap of new customers, rather
+// than for each of them.
+const lowWaterFraction = 0.9
+
+// Option configures optional behaviour of a rate limiter.
+type Option func(*eviction)
+
+// WithMaxKeys caps the number of customers a rate limiter tracks. When a
+// new customer would exceed the cap, customers idle long enough for their
+// history to no longer matter are evicted first, then the least recently
+// active ones until a tenth of the cap is free, which forgets their
+// requests. Zero means no cap.
+func WithMaxKeys(n int) Option {
+	return func(e *eviction) {
+		e.maxKeys = n
+	}
+}
+
+// eviction bounds the memory of a rate limiter by forgetting idle
+// customers. Time is measured by the event times of the requests seen, so
+// that replaying old samples evicts as it would have live, but never runs
+// ahead of the wall clock, so that a single sample dated in the future does
+// not make every customer look idle.
+type eviction struct {
+	maxKeys    int
+	highWater  time.Time        // Latest event time seen, at most the current time
+	sinceSweep int              // Requests since the last sweep
+	now        func() time.Time // Current time, replaceable in tests
+}
+
+// newEviction applies opts to the eviction settings of a limiter.
+func newEviction(opts []Option) eviction {
+	e := eviction{now: time.Now}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return e
+}
+
+// observe records a request at at for a limiter tracking n keys, and
+// reports whether it is time to sweep the idle keys.
+func (e *eviction) observe(at time.Time, n int) bool {
+	if now := e.now(); at.After(now) {
+		at = now
+	}
+	if at.After(e.highWater) {
+		e.highWater = at
+	}
+	e.sinceSweep++
+	return e.sinceSweep >= max(n, minSweepInterval)
+}
+
+// full reports whether a limiter tracking n keys has no room for another.
+func (e *eviction) full(n int) bool {
+	return e.maxKeys > 0 && n >= e.maxKeys
+}
+
+// lowWater returns the number of keys a limiter at the cap evicts down to.
+func (e *eviction) lowWater() int {
+	return min(int(float64(e.maxKeys)*lowWaterFraction), e.maxKeys-1)
+}
+
+// sweep deletes the keys of m that idle reports as idle. If makeRoom is set
+// and m is still above the low-water mark, it then deletes the keys with
+// the oldest lastActive down to the mark.
+func sweep[V any](e *eviction, m map[string]V, makeRoom bool, idle func(customerID string, v V) bool, lastActive func(v V) time.Time) {
+	e.sinceSweep = 0
+	for customerID, v := range m {
+		if idle(customerID, v) {
+			delete(m, customerID)
+		}
+	}
+	if !makeRoom || e.maxKeys <= 0 || len(m) <= e.lowWater() {
+		return
+	}
+
+	type key struct {
+		customerID string
+		lastActive time.Time
+	}
+	keys := make([]key, 0, len(m))
+	for customerID, v := range m {
+		keys = append(keys, key{customerID, lastActive(v)})
+	}
+	slices.SortFunc(keys, func(a, b key) int { return a.lastActive.Compare(b.lastActive) })
+	for _, k := range keys[:len(m)-e.lowWater()] {
+		delete(m, k.customerID)
+	}
+}
@@ -0,0 +1,133 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// trackingLimiter is a rate limiter reporting its tracked keys.
+type trackingLimiter interface {
+	IsAllowed(customerID string, at time.Time) bool
+	TrackedKeys() int
+}
+
+func TestEvictIdleKeys(t *testing.T) {
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	for name, limiter := range map[string]trackingLimiter{
+		"sliding log":  NewRateLimiter(5),
+		"token bucket": NewTokenBucket(5, 5),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Test 1: Every customer seen is tracked
+			for i := 0; i < 100; i++ {
+				limiter.IsAllowed(fmt.Sprintf("idle%d", i), baseTime)
+			}
+			if keys := limiter.TrackedKeys(); keys != 100 {
+				t.Errorf("Expected 100 tracked keys, got %d", keys)
+			}
+
+			// Test 2: Once event time has moved past their windows, the idle
+			// customers are evicted by a later sweep
+			later := baseTime.Add(time.Hour)
+			for i := 0; i < minSweepInterval; i++ {
+				limiter.IsAllowed("active", later)
+			}
+			if keys := limiter.TrackedKeys(); keys != 1 {
+				t.Errorf("Expected only the active customer to be tracked, got %d keys", keys)
+			}
+		})
+	}
+}
+
+func TestEvictKeepsRecentKeys(t *testing.T) {
+	limiter := NewRateLimiter(5)
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	limiter.IsAllowed("recent", baseTime)
+	for i := 0; i < minSweepInterval; i++ {
+		limiter.IsAllowed("active", baseTime.Add(30*time.Second))
+	}
+	if keys := limiter.TrackedKeys(); keys != 2 {
+		t.Errorf("Expected a customer within their window to be kept, got %d keys", keys)
+	}
+}
+
+func TestEvictIgnoresFutureSamples(t *testing.T) {
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+	now := func() time.Time { return baseTime.Add(5 * time.Second) }
+	slidingLog, tokenBucket := NewRateLimiter(5), NewTokenBucket(5, 5)
+	slidingLog.eviction.now, tokenBucket.eviction.now = now, now
+
+	for name, limiter := range map[string]trackingLimiter{
+		"sliding log":  slidingLog,
+		"token bucket": tokenBucket,
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				limiter.IsAllowed(fmt.Sprintf("cust%d", i), baseTime)
+			}
+
+			// A sample dated a day ahead does not make everyone else idle
+			limiter.IsAllowed("future", baseTime.Add(24*time.Hour))
+			for i := 0; i < minSweepInterval; i++ {
+				limiter.IsAllowed("active", baseTime)
+			}
+			if keys := limiter.TrackedKeys(); keys != 102 {
+				t.Errorf("Expected every customer within their window to be kept, got %d keys", keys)
+			}
+		})
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	for name, limiter := range map[string]trackingLimiter{
+		"sliding log":  NewRateLimiter(1, WithMaxKeys(3)),
+		"token bucket": NewTokenBucket(1, 1, WithMaxKeys(3)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Customers all active within the window: the least recently
+			// active is evicted to make room
+			for i := 0; i < 4; i++ {
+				limiter.IsAllowed(fmt.Sprintf("cust%d", i), baseTime.Add(time.Duration(i)*time.Second))
+			}
+			if keys := limiter.TrackedKeys(); keys != 3 {
+				t.Errorf("Expected the cap of 3 tracked keys, got %d", keys)
+			}
+			at := baseTime.Add(10 * time.Second)
+			if !limiter.IsAllowed("cust0", at) {
+				t.Error("The evicted customer should have been forgotten")
+			}
+			if limiter.IsAllowed("cust3", at) {
+				t.Error("The most recently active customer should have been kept")
+			}
+		})
+	}
+}
+
+func TestMaxKeysEvictsToLowWater(t *testing.T) {
+	limiter := NewRateLimiter(1, WithMaxKeys(100))
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Reaching the cap evicts the least recently active tenth at once, so
+	// that the following new customers fit without another eviction
+	for i := 0; i < 110; i++ {
+		limiter.IsAllowed(fmt.Sprintf("cust%d", i), baseTime.Add(time.Duration(i)*time.Millisecond))
+		want := i + 1
+		if i >= 100 {
+			want = i - 9
+		}
+		if limiter.TrackedKeys() != want {
+			t.Fatalf("After customer %d expected %d tracked keys, got %d", i, want, limiter.TrackedKeys())
+		}
+	}
+	if limiter.IsAllowed("cust10", baseTime.Add(time.Second)) {
+		t.Error("Customers above the low-water mark should have been kept")
+	}
+	if !limiter.IsAllowed("cust9", baseTime.Add(time.Second)) {
+		t.Error("The least recently active customers should have been forgotten")
+	}
+}
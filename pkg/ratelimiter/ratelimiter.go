@@ -1,39 +1,73 @@
 package ratelimiter
 
 import (
+	"math"
+	"slices"
 	"sync"
 	"time"
 )
 
-// RateLimiter tracks requests per customer ID within a 1-minute window
+// RateLimiter tracks requests per customer ID within a sliding window for
+// each of the customer's limits
 type RateLimiter struct {
-	requestsPerMinute int
-	mu                sync.RWMutex
-	requests          map[string][]time.Time
+	tiers    *Tiers
+	mu       sync.RWMutex
+	requests map[string][]time.Time
+	eviction eviction
 }
 
 // NewRateLimiter creates a new rate limiter with the specified requests per minute limit
-func NewRateLimiter(requestsPerMinute int) *RateLimiter {
+func NewRateLimiter(requestsPerMinute int, opts ...Option) *RateLimiter {
+	return NewTieredRateLimiter(uniformTiers(requestsPerMinute, time.Minute, 0), opts...)
+}
+
+// NewTieredRateLimiter creates a new rate limiter applying the limits the
+// tiers resolve for each customer
+func NewTieredRateLimiter(tiers *Tiers, opts ...Option) *RateLimiter {
 	return &RateLimiter{
-		requestsPerMinute: requestsPerMinute,
-		requests:          make(map[string][]time.Time),
+		tiers:    tiers,
+		requests: make(map[string][]time.Time),
+		eviction: newEviction(opts),
 	}
 }
 
-// IsAllowed checks if a request is allowed based on rate limits within a 1-minute window
+// IsAllowed checks if a request is allowed based on each of the customer's limits within its window
 func (r *RateLimiter) IsAllowed(customerID string, createdAt time.Time) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	return r.isAllowed(customerID, createdAt)
+}
+
+// Allow checks if a request is allowed like IsAllowed, and returns the
+// number of requests the customer has left at createdAt, counting this one.
+func (r *RateLimiter) Allow(customerID string, createdAt time.Time) (allowed bool, remaining int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	allowed = r.isAllowed(customerID, createdAt)
+	return allowed, r.remaining(customerID, createdAt)
+}
+
+// isAllowed implements IsAllowed with r.mu held.
+func (r *RateLimiter) isAllowed(customerID string, createdAt time.Time) bool {
+	limits := r.tiers.limits(customerID)
+	if r.eviction.observe(createdAt, len(r.requests)) {
+		r.sweep(false)
+	}
+
 	// Initialize if customer doesn't exist
 	if _, exists := r.requests[customerID]; !exists {
+		if r.eviction.full(len(r.requests)) {
+			r.sweep(true)
+		}
 		r.requests[customerID] = []time.Time{createdAt}
 		return true
 	}
 
-	// Get requests within the last 60 seconds
+	// Get requests within the longest window
 	var validRequests []time.Time
-	windowStart := createdAt.Add(-1 * time.Minute)
+	windowStart := createdAt.Add(-limits[len(limits)-1].window)
 
 	// Keep track of requests in the sliding window
 	for _, t := range r.requests[customerID] {
@@ -45,32 +79,118 @@ func (r *RateLimiter) IsAllowed(customerID string, createdAt time.Time) bool {
 	// Update the requests list with only valid ones
 	r.requests[customerID] = validRequests
 
-	// Check if under limit
-	if len(validRequests) < r.requestsPerMinute {
-		r.requests[customerID] = append(r.requests[customerID], createdAt)
-		return true
+	// Check if under every limit
+	for _, l := range limits {
+		if countSince(validRequests, createdAt.Add(-l.window)) >= l.requests {
+			return false
+		}
 	}
-
-	return false
+	r.requests[customerID] = append(r.requests[customerID], createdAt)
+	return true
 }
 
-// GetRemainingRequests returns the number of remaining requests allowed within the current minute
+// GetRemainingRequests returns the number of remaining requests allowed within the customer's current windows
 func (r *RateLimiter) GetRemainingRequests(customerID string) int {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	now := time.Now()
-	windowStart := now.Add(-1 * time.Minute)
-	var validCount int
+	return r.remaining(customerID, time.Now())
+}
+
+// remaining returns the number of requests the customer has left at at in
+// their fullest window.
+func (r *RateLimiter) remaining(customerID string, at time.Time) int {
+	remaining := math.MaxInt
+	for _, l := range r.tiers.limits(customerID) {
+		remaining = min(remaining, l.requests-countSince(r.requests[customerID], at.Add(-l.window)))
+	}
+	return remaining
+}
+
+// RetryAfter returns how long after at the customer has to wait before a
+// request would be allowed again, or zero if a request at at is allowed.
+func (r *RateLimiter) RetryAfter(customerID string, at time.Time) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, wait := r.exceeded(customerID, at)
+	return wait
+}
+
+// ExceededLimit returns the limit, as a number of requests per window,
+// that keeps the customer waiting longest at at, or zeros if a request at
+// at is allowed.
+func (r *RateLimiter) ExceededLimit(customerID string, at time.Time) (requests int, window time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, _ := r.exceeded(customerID, at)
+	return l.requests, l.window
+}
 
-	if times, exists := r.requests[customerID]; exists {
-		// Count requests within the last 60 seconds
-		for _, t := range times {
+// TrackedKeys returns the number of customers whose requests are tracked.
+func (r *RateLimiter) TrackedKeys() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.requests)
+}
+
+// sweep evicts the customers whose newest request has left every window
+// by the high-water mark, and, to make room for a new customer, the least
+// recently active ones down to the low-water mark.
+func (r *RateLimiter) sweep(makeRoom bool) {
+	sweep(&r.eviction, r.requests, makeRoom, func(customerID string, times []time.Time) bool {
+		limits := r.tiers.limits(customerID)
+		return newest(times).Before(r.eviction.highWater.Add(-limits[len(limits)-1].window))
+	}, newest)
+}
+
+// newest returns the latest of times.
+func newest(times []time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// exceeded returns the customer's limit with the longest wait at at, and
+// the wait.
+func (r *RateLimiter) exceeded(customerID string, at time.Time) (limit, time.Duration) {
+	var longest limit
+	var longestWait time.Duration
+	for _, l := range r.tiers.limits(customerID) {
+		windowStart := at.Add(-l.window)
+		var valid []time.Time
+		for _, t := range r.requests[customerID] {
 			if t.After(windowStart) || t.Equal(windowStart) {
-				validCount++
+				valid = append(valid, t)
 			}
 		}
+		if len(valid) < l.requests {
+			continue
+		}
+
+		// The window allows a request again once enough of the oldest
+		// requests have left it
+		slices.SortFunc(valid, time.Time.Compare)
+		if wait := valid[len(valid)-l.requests].Add(l.window).Sub(at) + time.Nanosecond; wait > longestWait {
+			longest, longestWait = l, wait
+		}
 	}
+	return longest, longestWait
+}
 
-	return r.requestsPerMinute - validCount
+// countSince counts the times at or after start.
+func countSince(times []time.Time, start time.Time) int {
+	var n int
+	for _, t := range times {
+		if t.After(start) || t.Equal(start) {
+			n++
+		}
+	}
+	return n
 }
@@ -170,3 +170,28 @@ func BenchmarkRateLimiterParallel(b *testing.B) {
 		}
 	})
 }
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	limiter := NewRateLimiter(2) // 2 requests per minute
+	customerID := "test123"
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Test 1: Under the limit there is no need to wait
+	if wait := limiter.RetryAfter(customerID, baseTime); wait != 0 {
+		t.Errorf("Expected no wait, got %v", wait)
+	}
+
+	// Test 2: At the limit, wait until the oldest request leaves the window
+	limiter.IsAllowed(customerID, baseTime)
+	limiter.IsAllowed(customerID, baseTime.Add(20*time.Second))
+	at := baseTime.Add(30 * time.Second)
+	wait := limiter.RetryAfter(customerID, at)
+	if wait <= 30*time.Second || wait > 31*time.Second {
+		t.Errorf("Expected a wait of just over 30s, got %v", wait)
+	}
+
+	// Test 3: A request after waiting should be allowed
+	if !limiter.IsAllowed(customerID, at.Add(wait)) {
+		t.Error("Request after RetryAfter should be allowed")
+	}
+}
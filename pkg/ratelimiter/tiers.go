@@ -0,0 +1,211 @@
+package ratelimiter
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limit allows a number of requests per window.
+type Limit struct {
+	Requests int    `json:"requests" yaml:"requests"`
+	Window   string `json:"window,omitempty" yaml:"window,omitempty"` // Go duration such as 1m or 24h; defaults to 1m
+	Burst    int    `json:"burst,omitempty" yaml:"burst,omitempty"`   // Token bucket size; defaults to Requests
+}
+
+// CustomerLimit is the limit of a single customer: either the limits of a
+// named tier, or explicit limits of their own.
+type CustomerLimit struct {
+	Tier   string  `json:"tier,omitempty" yaml:"tier,omitempty"`
+	Limits []Limit `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// TierConfig configures Tiers. Each tier is a list of limits, all of which
+// are enforced, such as 5 a minute and 200 an hour.
+type TierConfig struct {
+	Default   []Limit                  `json:"default" yaml:"default"`     // Limits of customers without a tier or override
+	Tiers     map[string][]Limit       `json:"tiers" yaml:"tiers"`         // Limits keyed by tier name
+	Customers map[string]CustomerLimit `json:"customers" yaml:"customers"` // Tiers and overrides keyed by customer ID
+}
+
+// Tiers resolves the rate limits of each customer.
+type Tiers struct {
+	def       []limit
+	customers map[string][]limit
+}
+
+// limit is a Limit with its window parsed and defaults applied.
+type limit struct {
+	requests int
+	window   time.Duration
+	burst    int
+}
+
+// LoadTierConfig reads a tier table from a YAML (.yaml or .yml) or JSON file
+// of the form
+//
+//	default:
+//	  - {requests: 5, window: 1m}
+//	  - {requests: 200, window: 1h}
+//	tiers:
+//	  pro: [{requests: 100, window: 1m, burst: 200}]
+//	customers:
+//	  client-A: {tier: pro}
+//	  client-B: {limits: [{requests: 1000, window: 1h}]}
+func LoadTierConfig(path string) (TierConfig, error) {
+	var config TierConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading rate limit tiers: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return config, fmt.Errorf("error decoding rate limit tiers: %v", err)
+	}
+	return config, nil
+}
+
+// NewTiers compiles the tier table, resolving the limits of every customer
+// listed in it.
+func NewTiers(config TierConfig) (*Tiers, error) {
+	def, err := compileLimits(config.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default limits: %v", err)
+	}
+	t := &Tiers{def: def, customers: make(map[string][]limit, len(config.Customers))}
+
+	for customerID, c := range config.Customers {
+		limits := c.Limits
+		if c.Tier != "" {
+			tier, ok := config.Tiers[c.Tier]
+			if !ok {
+				return nil, fmt.Errorf("customer %s: unknown tier %q", customerID, c.Tier)
+			}
+			limits = tier
+		}
+		if t.customers[customerID], err = compileLimits(limits); err != nil {
+			return nil, fmt.Errorf("customer %s: %v", customerID, err)
+		}
+	}
+	return t, nil
+}
+
+// ParseLimit parses a limit written as requests/window, such as 200/1h.
+func ParseLimit(s string) (Limit, error) {
+	requests, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q is not of the form requests/window", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return Limit{}, fmt.Errorf("limit %q: invalid number of requests", s)
+	}
+	l := Limit{Requests: n, Window: window}
+	if _, err := l.compile(); err != nil {
+		return Limit{}, fmt.Errorf("limit %q: %v", s, err)
+	}
+	return l, nil
+}
+
+// uniformTiers gives every customer the same single limit.
+func uniformTiers(requests int, window time.Duration, burst int) *Tiers {
+	if burst == 0 {
+		burst = requests
+	}
+	return &Tiers{def: []limit{{requests: requests, window: window, burst: burst}}}
+}
+
+// Window is a resolved limit of a customer: Requests requests per Duration.
+type Window struct {
+	Requests int
+	Duration time.Duration
+}
+
+// Windows returns the limits of the customer, shortest first, for rate
+// limiters implemented outside this package.
+func (t *Tiers) Windows(customerID string) []Window {
+	limits := t.limits(customerID)
+	windows := make([]Window, len(limits))
+	for i, l := range limits {
+		windows[i] = Window{Requests: l.requests, Duration: l.window}
+	}
+	return windows
+}
+
+// HasBurst reports whether any limit of the tiers has a burst other than
+// its number of requests, which only the token bucket strategy honours.
+func (t *Tiers) HasBurst() bool {
+	for _, limits := range t.customers {
+		for _, l := range limits {
+			if l.burst != l.requests {
+				return true
+			}
+		}
+	}
+	for _, l := range t.def {
+		if l.burst != l.requests {
+			return true
+		}
+	}
+	return false
+}
+
+// limits returns the limits of the customer, shortest window first.
+func (t *Tiers) limits(customerID string) []limit {
+	if l, ok := t.customers[customerID]; ok {
+		return l
+	}
+	return t.def
+}
+
+// compileLimits compiles a list of limits, sorting them by window.
+func compileLimits(limits []Limit) ([]limit, error) {
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("no limits")
+	}
+	compiled := make([]limit, len(limits))
+	for i, l := range limits {
+		var err error
+		if compiled[i], err = l.compile(); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortStableFunc(compiled, func(a, b limit) int { return cmp.Compare(a.window, b.window) })
+	return compiled, nil
+}
+
+// compile parses the window of l and applies the defaults.
+func (l Limit) compile() (limit, error) {
+	if l.Requests < 1 {
+		return limit{}, fmt.Errorf("requests must be positive, got %d", l.Requests)
+	}
+	c := limit{requests: l.Requests, window: time.Minute, burst: l.Requests}
+	if l.Window != "" {
+		window, err := time.ParseDuration(l.Window)
+		if err != nil {
+			return limit{}, fmt.Errorf("invalid window: %v", err)
+		}
+		if window <= 0 {
+			return limit{}, fmt.Errorf("window must be positive, got %s", l.Window)
+		}
+		c.window = window
+	}
+	if l.Burst != 0 {
+		c.burst = l.Burst
+	}
+	return c, nil
+}
@@ -0,0 +1,222 @@
+package ratelimiter
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/interfaces"
+)
+
+func TestLoadTierConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiers.yaml")
+	data := `
+default:
+  - {requests: 200, window: 1h}
+  - {requests: 5}
+tiers:
+  pro: [{requests: 100, window: 1m, burst: 200}]
+customers:
+  client-A: {tier: pro}
+  client-B: {limits: [{requests: 1000, window: 1h}]}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write tiers: %v", err)
+	}
+
+	config, err := LoadTierConfig(path)
+	if err != nil {
+		t.Fatalf("LoadTierConfig() error = %v", err)
+	}
+	tiers, err := NewTiers(config)
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+
+	tests := []struct {
+		customerID string
+		want       []limit // Shortest window first
+	}{
+		{"client-A", []limit{{requests: 100, window: time.Minute, burst: 200}}},
+		{"client-B", []limit{{requests: 1000, window: time.Hour, burst: 1000}}},
+		{"client-C", []limit{{requests: 5, window: time.Minute, burst: 5}, {requests: 200, window: time.Hour, burst: 200}}},
+	}
+	for _, tt := range tests {
+		if got := tiers.limits(tt.customerID); !slices.Equal(got, tt.want) {
+			t.Errorf("limits(%q) = %+v, want %+v", tt.customerID, got, tt.want)
+		}
+	}
+}
+
+func TestNewTiersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TierConfig
+		wantErr string
+	}{
+		{"No default", TierConfig{}, "default limits: no limits"},
+		{"Unknown tier", TierConfig{
+			Default:   []Limit{{Requests: 5}},
+			Customers: map[string]CustomerLimit{"client-A": {Tier: "gold"}},
+		}, `unknown tier "gold"`},
+		{"Invalid window", TierConfig{
+			Default:   []Limit{{Requests: 5}},
+			Customers: map[string]CustomerLimit{"client-A": {Limits: []Limit{{Requests: 5, Window: "soon"}}}},
+		}, "invalid window"},
+		{"Negative window", TierConfig{Default: []Limit{{Requests: 5, Window: "-1m"}}}, "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTiers(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewTiers() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTieredRateLimiter(t *testing.T) {
+	tiers, err := NewTiers(TierConfig{
+		Default: []Limit{{Requests: 2}},
+		Tiers:   map[string][]Limit{"pro": {{Requests: 4, Window: "1h"}}},
+		Customers: map[string]CustomerLimit{
+			"pro-customer": {Tier: "pro"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+	limiter := NewTieredRateLimiter(tiers)
+	baseTime := time.Now()
+
+	// Test 1: Each customer gets their own limit
+	for i := 0; i < 4; i++ {
+		limiter.IsAllowed("pro-customer", baseTime)
+		limiter.IsAllowed("free-customer", baseTime)
+	}
+	if limiter.IsAllowed("pro-customer", baseTime) {
+		t.Error("Pro customer's 5th request should be denied")
+	}
+	if remaining := limiter.GetRemainingRequests("free-customer"); remaining != 0 {
+		t.Errorf("Expected 0 remaining requests for the free customer, got %d", remaining)
+	}
+
+	// Test 2: Each customer's window applies
+	if !limiter.IsAllowed("free-customer", baseTime.Add(61*time.Second)) {
+		t.Error("Free customer's request after their 1-minute window should be allowed")
+	}
+	if limiter.IsAllowed("pro-customer", baseTime.Add(61*time.Second)) {
+		t.Error("Pro customer's request within their 1-hour window should be denied")
+	}
+	if wait := limiter.RetryAfter("pro-customer", baseTime.Add(time.Minute)); wait <= 59*time.Minute {
+		t.Errorf("Expected the pro customer to wait for their hour to pass, got %v", wait)
+	}
+}
+
+func TestTieredTokenBucket(t *testing.T) {
+	tiers, err := NewTiers(TierConfig{
+		Default: []Limit{{Requests: 1}},
+		Customers: map[string]CustomerLimit{
+			"client-A": {Limits: []Limit{{Requests: 60, Window: "1h", Burst: 3}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+	limiter := NewTieredTokenBucket(tiers)
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	if remaining := limiter.GetRemainingRequests("client-A"); remaining != 3 {
+		t.Errorf("Expected a burst of 3, got %d", remaining)
+	}
+	for i := 0; i < 3; i++ {
+		if !limiter.IsAllowed("client-A", baseTime) {
+			t.Errorf("Request %d of the burst should be allowed", i+1)
+		}
+	}
+	if wait := limiter.RetryAfter("client-A", baseTime); wait != time.Minute {
+		t.Errorf("Expected a token every minute, got a wait of %v", wait)
+	}
+	if !limiter.IsAllowed("client-B", baseTime) || limiter.IsAllowed("client-B", baseTime) {
+		t.Error("Customers without an override should get the default limit")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	if got, err := ParseLimit("200/1h"); err != nil || got != (Limit{Requests: 200, Window: "1h"}) {
+		t.Errorf("ParseLimit(\"200/1h\") = %+v, %v, want 200 per 1h", got, err)
+	}
+	for _, s := range []string{"200", "many/1h", "200/soon", "0/1m"} {
+		if _, err := ParseLimit(s); err == nil {
+			t.Errorf("ParseLimit(%q) error = nil, want an error", s)
+		}
+	}
+}
+
+func TestMultiWindowRateLimiter(t *testing.T) {
+	tiers, err := NewTiers(TierConfig{Default: []Limit{{Requests: 2}, {Requests: 3, Window: "1h"}}})
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	for name, limiter := range map[string]interfaces.WindowedRateLimiter{
+		"sliding log":  NewTieredRateLimiter(tiers),
+		"token bucket": NewTieredTokenBucket(tiers),
+	} {
+		t.Run(name, func(t *testing.T) {
+			customerID := "test123"
+
+			// Test 1: The minute window is exceeded first
+			limiter.IsAllowed(customerID, baseTime)
+			limiter.IsAllowed(customerID, baseTime)
+			if limiter.IsAllowed(customerID, baseTime) {
+				t.Error("3rd request within a minute should be denied")
+			}
+			if requests, window := limiter.ExceededLimit(customerID, baseTime); requests != 2 || window != time.Minute {
+				t.Errorf("ExceededLimit() = %d per %v, want 2 per 1m", requests, window)
+			}
+
+			// Test 2: The hour window is exceeded once the minute has passed
+			at := baseTime.Add(2 * time.Minute)
+			if !limiter.IsAllowed(customerID, at) {
+				t.Error("Request in the next minute should be allowed")
+			}
+			if limiter.IsAllowed(customerID, at.Add(time.Minute)) {
+				t.Error("4th request within an hour should be denied")
+			}
+			if requests, window := limiter.ExceededLimit(customerID, at.Add(time.Minute)); requests != 3 || window != time.Hour {
+				t.Errorf("ExceededLimit() = %d per %v, want 3 per 1h", requests, window)
+			}
+			if wait := limiter.RetryAfter(customerID, at.Add(time.Minute)); wait < 10*time.Minute {
+				t.Errorf("Expected to wait for the hour window, got %v", wait)
+			}
+		})
+	}
+}
+
+func TestHasBurst(t *testing.T) {
+	for _, tt := range []struct {
+		config TierConfig
+		want   bool
+	}{
+		{TierConfig{Default: []Limit{{Requests: 5}}}, false},
+		{TierConfig{Default: []Limit{{Requests: 5, Burst: 5}}}, false},
+		{TierConfig{Default: []Limit{{Requests: 5, Burst: 10}}}, true},
+		{TierConfig{
+			Default:   []Limit{{Requests: 5}},
+			Customers: map[string]CustomerLimit{"client-A": {Limits: []Limit{{Requests: 5, Burst: 2}}}},
+		}, true},
+	} {
+		tiers, err := NewTiers(tt.config)
+		if err != nil {
+			t.Fatalf("NewTiers() error = %v", err)
+		}
+		if got := tiers.HasBurst(); got != tt.want {
+			t.Errorf("HasBurst() of %+v = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits requests per customer ID to a steady refill rate with a
+// burst allowance, using the generic cell rate algorithm (GCRA). Unlike
+// RateLimiter it keeps a single timestamp per customer and limit, however
+// many requests they make.
+type TokenBucket struct {
+	tiers    *Tiers
+	mu       sync.Mutex
+	tats     map[string][]time.Time // Theoretical arrival time of each customer's next request, per limit
+	eviction eviction
+}
+
+// NewTokenBucket creates a token bucket refilling requestsPerMinute tokens a
+// minute and holding up to burst tokens, so that a customer idle for long
+// enough may make burst requests at once. A burst below 1 is treated as 1.
+func NewTokenBucket(requestsPerMinute, burst int, opts ...Option) *TokenBucket {
+	return NewTieredTokenBucket(uniformTiers(max(requestsPerMinute, 1), time.Minute, max(burst, 1)), opts...)
+}
+
+// NewTieredTokenBucket creates a token bucket per limit of each customer,
+// refilling the requests of the limit over its window and holding up to
+// its burst. A request needs a token from every bucket.
+func NewTieredTokenBucket(tiers *Tiers, opts ...Option) *TokenBucket {
+	return &TokenBucket{
+		tiers:    tiers,
+		tats:     make(map[string][]time.Time),
+		eviction: newEviction(opts),
+	}
+}
+
+// IsAllowed takes a token from each of the customer's buckets if all of
+// them have one available at createdAt.
+func (b *TokenBucket) IsAllowed(customerID string, createdAt time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.isAllowed(customerID, createdAt)
+}
+
+// Allow takes tokens like IsAllowed, and returns the number of tokens left
+// at createdAt in the customer's emptiest bucket.
+func (b *TokenBucket) Allow(customerID string, createdAt time.Time) (allowed bool, remaining int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	allowed = b.isAllowed(customerID, createdAt)
+	return allowed, b.remaining(customerID, createdAt)
+}
+
+// isAllowed implements IsAllowed with b.mu held.
+func (b *TokenBucket) isAllowed(customerID string, createdAt time.Time) bool {
+	limits := b.tiers.limits(customerID)
+	if b.eviction.observe(createdAt, len(b.tats)) {
+		b.sweep(false)
+	}
+	for i, l := range limits {
+		if _, tolerance := l.rate(); b.tat(customerID, i, createdAt).Sub(createdAt) > tolerance {
+			return false
+		}
+	}
+
+	tats := b.tats[customerID]
+	if len(tats) != len(limits) {
+		if _, exists := b.tats[customerID]; !exists && b.eviction.full(len(b.tats)) {
+			b.sweep(true)
+		}
+		tats = make([]time.Time, len(limits))
+		b.tats[customerID] = tats
+	}
+	for i, l := range limits {
+		interval, _ := l.rate()
+		tats[i] = b.tat(customerID, i, createdAt).Add(interval)
+	}
+	return true
+}
+
+// GetRemainingRequests returns the number of tokens left now in the
+// customer's emptiest bucket.
+func (b *TokenBucket) GetRemainingRequests(customerID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remaining(customerID, time.Now())
+}
+
+// remaining returns the number of tokens left at at in the customer's
+// emptiest bucket.
+func (b *TokenBucket) remaining(customerID string, at time.Time) int {
+	remaining := math.MaxInt
+	for i, l := range b.tiers.limits(customerID) {
+		interval, tolerance := l.rate()
+		ahead := b.tat(customerID, i, at).Sub(at) // Time the customer has borrowed against the refill rate
+		if ahead > tolerance {
+			return 0
+		}
+		remaining = min(remaining, int((tolerance-ahead)/interval)+1)
+	}
+	return remaining
+}
+
+// RetryAfter returns how long after at the customer has to wait for a
+// token from each bucket, or zero if all have one available at at.
+func (b *TokenBucket) RetryAfter(customerID string, at time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, wait := b.exceeded(customerID, at)
+	return wait
+}
+
+// ExceededLimit returns the limit, as a number of requests per window,
+// whose bucket keeps the customer waiting longest at at, or zeros if a
+// request at at is allowed.
+func (b *TokenBucket) ExceededLimit(customerID string, at time.Time) (requests int, window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	l, _ := b.exceeded(customerID, at)
+	return l.requests, l.window
+}
+
+// TrackedKeys returns the number of customers whose buckets are tracked.
+func (b *TokenBucket) TrackedKeys() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.tats)
+}
+
+// sweep evicts the customers whose buckets have refilled by the
+// high-water mark, and, to make room for a new customer, the least recently
+// active ones down to the low-water mark.
+func (b *TokenBucket) sweep(makeRoom bool) {
+	sweep(&b.eviction, b.tats, makeRoom, func(_ string, tats []time.Time) bool {
+		return !newest(tats).After(b.eviction.highWater)
+	}, newest)
+}
+
+// exceeded returns the customer's limit with the longest wait for a token
+// at at, and the wait.
+func (b *TokenBucket) exceeded(customerID string, at time.Time) (limit, time.Duration) {
+	var longest limit
+	var longestWait time.Duration
+	for i, l := range b.tiers.limits(customerID) {
+		_, tolerance := l.rate()
+		if wait := b.tat(customerID, i, at).Sub(at) - tolerance; wait > longestWait {
+			longest, longestWait = l, wait
+		}
+	}
+	return longest, longestWait
+}
+
+// tat returns the theoretical arrival time of the customer's next request
+// for their i-th limit, which is at if its bucket is full.
+func (b *TokenBucket) tat(customerID string, i int, at time.Time) time.Time {
+	if tats := b.tats[customerID]; i < len(tats) && tats[i].After(at) {
+		return tats[i]
+	}
+	return at
+}
+
+// rate returns the time for one token of the limit to refill, and how far
+// ahead of the refill rate requests may run.
+func (l limit) rate() (interval, tolerance time.Duration) {
+	interval = l.window / time.Duration(l.requests)
+	return interval, interval * time.Duration(max(l.burst, 1)-1)
+}
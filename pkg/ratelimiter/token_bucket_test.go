@@ -0,0 +1,161 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	limiter := NewTokenBucket(5, 10) // 5 requests per minute with a burst of 10
+	customerID := "test123"
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Test 1: A full bucket allows the whole burst at once
+	for i := 0; i < 10; i++ {
+		if !limiter.IsAllowed(customerID, baseTime) {
+			t.Errorf("Request %d of the burst should be allowed", i+1)
+		}
+	}
+
+	// Test 2: The next request should be denied
+	if limiter.IsAllowed(customerID, baseTime) {
+		t.Error("Request after the burst should be denied")
+	}
+
+	// Test 3: One token refills every 12 seconds
+	if limiter.IsAllowed(customerID, baseTime.Add(11*time.Second)) {
+		t.Error("Request before a token refilled should be denied")
+	}
+	if !limiter.IsAllowed(customerID, baseTime.Add(12*time.Second)) {
+		t.Error("Request after a token refilled should be allowed")
+	}
+	if limiter.IsAllowed(customerID, baseTime.Add(12*time.Second)) {
+		t.Error("Only one token should have refilled")
+	}
+}
+
+func TestTokenBucketSteadyRate(t *testing.T) {
+	limiter := NewTokenBucket(5, 1) // 5 requests per minute without bursts
+	customerID := "test123"
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Requests spaced at the refill rate are always allowed
+	for i := 0; i < 10; i++ {
+		requestTime := baseTime.Add(time.Duration(i) * 12 * time.Second)
+		if !limiter.IsAllowed(customerID, requestTime) {
+			t.Errorf("Request %d at %v should be allowed", i+1, requestTime)
+		}
+	}
+
+	// Requests faster than the refill rate are not
+	if limiter.IsAllowed(customerID, baseTime.Add(9*12*time.Second+6*time.Second)) {
+		t.Error("Request faster than the refill rate should be denied")
+	}
+}
+
+func TestTokenBucketMultipleCustomers(t *testing.T) {
+	limiter := NewTokenBucket(5, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !limiter.IsAllowed("cust1", now) {
+			t.Errorf("Customer 1 request %d should be allowed", i+1)
+		}
+	}
+	if limiter.IsAllowed("cust1", now) {
+		t.Error("Customer 1's 3rd request should be denied")
+	}
+	if !limiter.IsAllowed("cust2", now) {
+		t.Error("Customer 2 should still be able to make requests")
+	}
+}
+
+func TestTokenBucketRetryAfter(t *testing.T) {
+	limiter := NewTokenBucket(6, 2) // A token every 10 seconds
+	customerID := "test123"
+	baseTime := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	// Test 1: With tokens left there is no need to wait
+	if wait := limiter.RetryAfter(customerID, baseTime); wait != 0 {
+		t.Errorf("Expected no wait, got %v", wait)
+	}
+
+	// Test 2: With the bucket empty, wait for the next token
+	limiter.IsAllowed(customerID, baseTime)
+	limiter.IsAllowed(customerID, baseTime)
+	at := baseTime.Add(4 * time.Second)
+	wait := limiter.RetryAfter(customerID, at)
+	if wait != 6*time.Second {
+		t.Errorf("Expected a wait of 6s, got %v", wait)
+	}
+
+	// Test 3: A request after waiting should be allowed
+	if !limiter.IsAllowed(customerID, at.Add(wait)) {
+		t.Error("Request after RetryAfter should be allowed")
+	}
+}
+
+func TestTokenBucketRemainingRequests(t *testing.T) {
+	limiter := NewTokenBucket(5, 3)
+	customerID := "test123"
+	now := time.Now()
+
+	if remaining := limiter.GetRemainingRequests(customerID); remaining != 3 {
+		t.Errorf("Expected 3 remaining requests, got %d", remaining)
+	}
+	limiter.IsAllowed(customerID, now)
+	limiter.IsAllowed(customerID, now)
+	if remaining := limiter.GetRemainingRequests(customerID); remaining != 1 {
+		t.Errorf("Expected 1 remaining request, got %d", remaining)
+	}
+	limiter.IsAllowed(customerID, now)
+	if remaining := limiter.GetRemainingRequests(customerID); remaining != 0 {
+		t.Errorf("Expected 0 remaining requests, got %d", remaining)
+	}
+}
+
+func TestConfigNew(t *testing.T) {
+	tests := []struct {
+		config   Config
+		wantType string // Type of the created limiter, or "" for an error
+	}{
+		{Config{RequestsPerMinute: 5}, "*ratelimiter.RateLimiter"},
+		{Config{Strategy: StrategySlidingLog, RequestsPerMinute: 5}, "*ratelimiter.RateLimiter"},
+		{Config{Strategy: StrategyTokenBucket, RequestsPerMinute: 5, Burst: 10}, "*ratelimiter.TokenBucket"},
+		{Config{RequestsPerMinute: 5, Burst: 5}, "*ratelimiter.RateLimiter"},
+		{Config{RequestsPerMinute: 5, Burst: 10}, ""},
+		{Config{Strategy: "leaky", RequestsPerMinute: 5}, ""},
+		{Config{Strategy: StrategyTokenBucket}, ""},
+	}
+
+	for _, tt := range tests {
+		limiter, err := tt.config.New()
+		if tt.wantType == "" {
+			if err == nil {
+				t.Errorf("%+v.New() error = nil, want an error", tt.config)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprintf("%T", limiter) != tt.wantType {
+			t.Errorf("%+v.New() = %T, %v, want %s", tt.config, limiter, err, tt.wantType)
+		}
+	}
+
+	// The burst defaults to the rate
+	limiter, _ := Config{Strategy: StrategyTokenBucket, RequestsPerMinute: 4}.New()
+	if remaining := limiter.GetRemainingRequests("test123"); remaining != 4 {
+		t.Errorf("Expected a default burst of 4, got %d", remaining)
+	}
+}
+
+func BenchmarkTokenBucket(b *testing.B) {
+	limiter := NewTokenBucket(1000, 1000) // High limit for benchmark
+	customerID := "bench123"
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		limiter.IsAllowed(customerID, now)
+	}
+}
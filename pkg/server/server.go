@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gohighlevel/pkg/service"
+	"gohighlevel/pkg/types"
+)
+
+const (
+	maxSampleBytes = 1 << 20  // Maximum body size of a single-sample request
+	maxBatchBytes  = 32 << 20 // Maximum body size of a batch request
+	maxBatchSize   = 1000     // Maximum number of samples in a batch request
+)
+
+// Server exposes a SampleService over HTTP so that other services can push
+// samples in real time. Request bodies are decoded with the service's field
+// mapping, so unknown fields are kept as attributes:
+//
+//	POST /samples        ingests a single sample
+//	POST /samples:batch  ingests {"samples": [...]} and reports on each item
+type Server struct {
+	service *service.SampleService
+	mux     *http.ServeMux
+}
+
+// Result describes the outcome of ingesting one sample. Status is the HTTP
+// status code the sample would have received on its own.
+type Result struct {
+	Index      int                    `json:"index"`
+	CustomerID string                 `json:"customerId"`
+	Status     int                    `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Errors     types.ValidationErrors `json:"errors,omitempty"` // Every validation failure of an invalid sample
+}
+
+// BatchResponse is the response body of POST /samples:batch.
+type BatchResponse struct {
+	SuccessCount int      `json:"successCount"`
+	ErrorCount   int      `json:"errorCount"`
+	Results      []Result `json:"results"`
+}
+
+// NewServer creates an HTTP server in front of s.
+func NewServer(s *service.SampleService) *Server {
+	srv := &Server{
+		service: s,
+		mux:     http.NewServeMux(),
+	}
+	srv.mux.HandleFunc("POST /samples", srv.handleSample)
+	srv.mux.HandleFunc("POST /samples:batch", srv.handleBatch)
+	return srv
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleSample ingests the single sample in the request body.
+func (s *Server) handleSample(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := decodeBody(w, r, maxSampleBytes, &raw); err != nil {
+		writeJSON(w, bodyStatus(err), Result{Status: bodyStatus(err), Error: err.Error()})
+		return
+	}
+	cs, err := s.service.DecodeSample(raw)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Result{Status: http.StatusBadRequest, Error: err.Error()})
+		return
+	}
+
+	remaining, err := s.service.ProcessSampleRemaining(cs)
+	result := newResult(0, cs.CustomerID, err)
+
+	// The remaining requests are those at the sample's event time, as
+	// counted along with it
+	if remaining >= 0 {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	var rateLimitErr *service.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(rateLimitErr)))
+	}
+	writeJSON(w, result.Status, result)
+}
+
+// handleBatch ingests every sample in the request body. Samples are
+// processed in order and independently: the response is 200 OK as long as
+// the body could be decoded, with the outcome of each sample in Results.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Samples []json.RawMessage `json:"samples"`
+	}
+	if err := decodeBody(w, r, maxBatchBytes, &body); err != nil {
+		writeJSON(w, bodyStatus(err), Result{Status: bodyStatus(err), Error: err.Error()})
+		return
+	}
+	if len(body.Samples) > maxBatchSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge, Result{
+			Status: http.StatusRequestEntityTooLarge,
+			Error:  "batch exceeds " + strconv.Itoa(maxBatchSize) + " samples",
+		})
+		return
+	}
+
+	resp := BatchResponse{Results: make([]Result, 0, len(body.Samples))}
+	for i, raw := range body.Samples {
+		var result Result
+		if cs, err := s.service.DecodeSample(raw); err != nil {
+			result = Result{Index: i, Status: http.StatusBadRequest, Error: err.Error()}
+		} else {
+			result = newResult(i, cs.CustomerID, s.service.ProcessSample(cs))
+		}
+		if result.Error == "" {
+			resp.SuccessCount++
+		} else {
+			resp.ErrorCount++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// newResult builds the result for a sample from the error ProcessSample returned.
+func newResult(index int, customerID string, err error) Result {
+	result := Result{Index: index, CustomerID: customerID, Status: statusCode(err)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	errors.As(err, &result.Errors)
+	return result
+}
+
+// statusCode maps an error returned by ProcessSample to an HTTP status code.
+func statusCode(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusCreated
+	case errors.Is(err, service.ErrInvalidSample):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrStorage):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// bodyStatus maps an error decoding a request body to an HTTP status code.
+func bodyStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// retryAfterSeconds rounds the wait of a rate limit error up to whole
+// seconds, as required by the Retry-After header.
+func retryAfterSeconds(err *service.RateLimitError) int {
+	return max(int(math.Ceil(err.RetryAfter.Seconds())), 1)
+}
+
+// decodeBody decodes the JSON request body into v, rejecting bodies larger
+// than limit bytes.
+func decodeBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(v)
+}
+
+// writeJSON writes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
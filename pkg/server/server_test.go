@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/service"
+	"gohighlevel/pkg/types"
+	"gohighlevel/pkg/validator"
+)
+
+// mockDB implements the Database interface for testing
+type mockDB struct {
+	err error // Error returned by InsertSample
+}
+
+func (m *mockDB) Init() error                            { return nil }
+func (m *mockDB) Close()                                 {}
+func (m *mockDB) InsertSample(sample types.Sample) error { return m.err }
+
+// Helper function to create a test server
+func setupTestServer(t *testing.T, db *mockDB) *Server {
+	t.Cleanup(func() { os.Remove("error.log") })
+
+	r := ratelimiter.NewRateLimiter(5)
+	s := service.NewSampleService(validator.NewValidator(db), r, db)
+	return NewServer(s)
+}
+
+func sampleJSON(customerID, email string) string {
+	return `{"customerId": "` + customerID + `", "name": "Test User", "email": "` + email +
+		`", "createdAt": "` + time.Now().Format(time.RFC3339) + `"}`
+}
+
+func post(srv *Server, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestPostSample(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid sample", sampleJSON("1", "test@example.com"), http.StatusCreated},
+		{"invalid email", sampleJSON("2", "not-an-email"), http.StatusBadRequest},
+		{"malformed body", `{"customerId": `, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(srv, "/samples", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestPostSampleRateLimited(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{})
+
+	for i := 0; i < 5; i++ {
+		rec := post(srv, "/samples", sampleJSON("rate-test", "rate@example.com"))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Request %d: expected status 201, got %d", i+1, rec.Code)
+		}
+		if got, want := rec.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(4-i); got != want {
+			t.Errorf("Request %d: expected X-RateLimit-Remaining %s, got %s", i+1, want, got)
+		}
+	}
+
+	rec := post(srv, "/samples", sampleJSON("rate-test", "rate@example.com"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected X-RateLimit-Remaining 0, got %s", got)
+	}
+}
+
+func TestPostSampleBackdatedRateLimited(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{})
+	body := `{"customerId": "backdated", "name": "Test User", "email": "old@example.com", "createdAt": "` +
+		time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}`
+
+	// The remaining requests are counted at the sample's event time, an hour
+	// ago, rather than now
+	for i := 0; i < 5; i++ {
+		rec := post(srv, "/samples", body)
+		if got, want := rec.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(4-i); rec.Code != http.StatusCreated || got != want {
+			t.Fatalf("Request %d: expected status 201 with X-RateLimit-Remaining %s, got %d with %s", i+1, want, rec.Code, got)
+		}
+	}
+	rec := post(srv, "/samples", body)
+	if got := rec.Header().Get("X-RateLimit-Remaining"); rec.Code != http.StatusTooManyRequests || got != "0" {
+		t.Errorf("Expected status 429 with X-RateLimit-Remaining 0, got %d with %s", rec.Code, got)
+	}
+}
+
+func TestPostSampleTooLarge(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{})
+
+	body := `{"customerId": "1", "name": "` + strings.Repeat("a", maxSampleBytes) + `"}`
+	if rec := post(srv, "/samples", body); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+}
+
+func TestPostSampleStorageError(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{err: errors.New("connection refused")})
+
+	rec := post(srv, "/samples", sampleJSON("1", "test@example.com"))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+}
+
+func TestPostBatch(t *testing.T) {
+	srv := setupTestServer(t, &mockDB{})
+
+	body := `{"samples": [` + sampleJSON("1", "one@example.com") + `, ` +
+		sampleJSON("2", "bad-email") + `, ` + sampleJSON("3", "three@example.com") + `]}`
+	rec := post(srv, "/samples:batch", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.SuccessCount != 2 || resp.ErrorCount != 1 {
+		t.Errorf("Expected 2 successes and 1 error, got %d and %d", resp.SuccessCount, resp.ErrorCount)
+	}
+
+	wantStatus := []int{http.StatusCreated, http.StatusBadRequest, http.StatusCreated}
+	if len(resp.Results) != len(wantStatus) {
+		t.Fatalf("Expected %d results, got %d", len(wantStatus), len(resp.Results))
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != wantStatus[i] {
+			t.Errorf("Result %d: expected index %d status %d, got %+v", i, i, wantStatus[i], result)
+		}
+	}
+	if errs := resp.Results[1].Errors; len(errs) != 1 || errs[0].Code != types.CodeInvalidEmail {
+		t.Errorf("Expected an invalid_email error for result 1, got %+v", errs)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress returns a reader that yields the decompressed content of r.
+// Compression is detected from the magic bytes at the start of the stream,
+// falling back to the file extension of path; uncompressed input is passed
+// through unchanged. The returned reader must be closed by the caller.
+func decompress(path string, r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zstdMagic))
+
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic), ext == ".gz" || ext == ".gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing gzip: %v", err)
+		}
+		return zr, nil
+	case bytes.HasPrefix(head, zstdMagic), ext == ".zst" || ext == ".zstd":
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing zstd: %v", err)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// formatExt returns the extension that identifies the input format of path,
+// ignoring any compression suffix: "samples.jsonl.gz" yields ".jsonl".
+func formatExt(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".gz", ".gzip", ".zst", ".zstd":
+		return strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	return ext
+}
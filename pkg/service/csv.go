@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ColumnMapping maps CSV/TSV header names onto CustomSample fields.
+// Keys are header names as they appear in the file and values are the
+// JSON names of the CustomSample fields: customerId, email, name,
+// createdAt and updatedAt. Header names are matched case-insensitively.
+// Other columns become attributes of the sample.
+type ColumnMapping map[string]string
+
+// csvReader decodes samples from delimiter-separated rows, using the
+// header row to work out which column feeds which CustomSample field.
+type csvReader struct {
+	r            *csv.Reader
+	mapping      ColumnMapping
+	fieldMapping FieldMapping
+	columns      []string // Record key of each column: a CustomSample field or the header
+}
+
+// newCSVReader creates a reader for rows separated by comma. Headers that
+// are not listed in the column mapping are matched against the CustomSample
+// field names directly; each row is then passed through the field mapping
+// like any other record.
+func newCSVReader(r io.Reader, comma rune, opts DecodeOptions) *csvReader {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1 // Row length is checked against the header in Next
+	cr.ReuseRecord = true
+
+	return &csvReader{r: cr, mapping: opts.ColumnMapping, fieldMapping: opts.FieldMapping}
+}
+
+// Next returns the record on the next row. It returns io.EOF once the input
+// is exhausted and a *RecordError for a row that cannot be parsed.
+func (c *csvReader) Next() (Record, error) {
+	if c.columns == nil {
+		if err := c.readHeader(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{}, &RecordError{Position: fmt.Sprintf("row %d", parseErr.StartLine), Err: parseErr.Err}
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("error reading file: %v", err)
+	}
+
+	row, _ := c.r.FieldPos(0)
+	if len(record) != len(c.columns) {
+		return Record{}, &RecordError{
+			Position: fmt.Sprintf("row %d", row),
+			Err:      fmt.Errorf("expected %d columns, got %d", len(c.columns), len(record)),
+		}
+	}
+
+	raw := make(map[string]interface{}, len(record))
+	for i, value := range record {
+		if value != "" { // Empty cells count as missing, so defaults apply
+			raw[c.columns[i]] = value
+		}
+	}
+
+	cs, err := c.fieldMapping.apply(raw)
+	if err != nil {
+		return Record{}, &RecordError{Position: fmt.Sprintf("row %d", row), Err: err}
+	}
+	return Record{Sample: cs, Position: fmt.Sprintf("row %d", row)}, nil
+}
+
+// readHeader reads the header row and resolves each column to the
+// CustomSample field it feeds, keeping the header name for other columns.
+// It fails if, after the field mapping, no column feeds any of the main
+// sample fields, as the header is then most likely not the expected one.
+func (c *csvReader) readHeader() error {
+	header, err := c.r.Read()
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("error reading header: %v", err)
+	}
+
+	// Spreadsheet exports often start with a UTF-8 byte order mark
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	c.columns = make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if field := c.resolve(name); field != "" {
+			c.columns[i] = field
+		} else {
+			c.columns[i] = name
+		}
+	}
+	if !c.fieldMapping.readsAny(c.columns, "customerId", "email", "name", "createdAt") {
+		return fmt.Errorf("error reading header: no column maps to a sample field")
+	}
+	return nil
+}
+
+// resolve returns the CustomSample field fed by the named column, or ""
+// if the column is not mapped.
+func (c *csvReader) resolve(column string) string {
+	for header, field := range c.mapping {
+		if strings.EqualFold(header, column) {
+			return canonicalField(field)
+		}
+	}
+	return canonicalField(column)
+}
+
+// canonicalField returns the CustomSample JSON field name matching name
+// case-insensitively, or "" if there is none.
+func canonicalField(name string) string {
+	for _, field := range sampleFields {
+		if strings.EqualFold(field, name) {
+			return field
+		}
+	}
+	return ""
+}
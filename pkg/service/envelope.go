@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// envelopeReader decodes samples from a {"samples": [...]} JSON document,
+// or from a bare top-level array, token by token. Only one array element
+// is held in memory at a time, so memory use stays flat regardless of file
+// size.
+type envelopeReader struct {
+	dec     *json.Decoder
+	mapping FieldMapping
+	inArray bool // Whether the decoder is positioned inside the samples array
+	done    bool // Whether the samples array has been fully consumed
+	index   int  // Index of the next array element
+}
+
+// newEnvelopeReader creates a reader that streams samples out of r.
+func newEnvelopeReader(r io.Reader, mapping FieldMapping) *envelopeReader {
+	return &envelopeReader{dec: json.NewDecoder(r), mapping: mapping}
+}
+
+// Next returns the next element of the samples array. It returns io.EOF once
+// the array is exhausted and a *RecordError for an element that cannot be
+// decoded into a CustomSample. Malformed JSON cannot be recovered from and
+// is returned as a plain error.
+func (e *envelopeReader) Next() (Record, error) {
+	if !e.inArray && !e.done {
+		if err := e.seekSamples(); err != nil {
+			return Record{}, fmt.Errorf("error decoding JSON: %v", err)
+		}
+	}
+	if e.done {
+		return Record{}, io.EOF
+	}
+
+	if !e.dec.More() {
+		// Consume the closing bracket of the samples array
+		if _, err := e.dec.Token(); err != nil {
+			return Record{}, fmt.Errorf("error decoding JSON: %v", err)
+		}
+		e.done = true
+		return Record{}, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := e.dec.Decode(&raw); err != nil {
+		return Record{}, fmt.Errorf("error decoding JSON: %v", err)
+	}
+	index := e.index
+	e.index++
+
+	cs, err := e.mapping.decodeRecord(raw)
+	if err != nil {
+		return Record{}, &RecordError{Position: fmt.Sprintf("index %d", index), Err: err}
+	}
+	return Record{Sample: cs, Position: fmt.Sprintf("index %d", index)}, nil
+}
+
+// seekSamples advances the decoder to the first element of the top-level
+// "samples" array, skipping over any other keys. A document that is itself
+// an array is read as the samples array. A document without a samples
+// array, or with a null one, yields no samples.
+func (e *envelopeReader) seekSamples() error {
+	tok, err := e.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('['):
+		e.inArray = true
+		return nil
+	case json.Delim('{'):
+	default:
+		return fmt.Errorf("expected an object or array, got %v", tok)
+	}
+
+	for e.dec.More() {
+		tok, err := e.dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, _ := tok.(string); key != "samples" {
+			var skip json.RawMessage
+			if err := e.dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tok, err = e.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case nil:
+			e.done = true
+		case json.Delim('['):
+			e.inArray = true
+		default:
+			return fmt.Errorf("samples must be an array, got %v", tok)
+		}
+		return nil
+	}
+
+	e.done = true
+	return nil
+}
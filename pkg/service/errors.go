@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned by ProcessSample, wrapped around the underlying cause so
+// that callers can tell why a sample was rejected with errors.Is.
+var (
+	ErrInvalidSample = errors.New("invalid sample")      // The sample failed parsing or validation
+	ErrRateLimited   = errors.New("rate limit exceeded") // The customer exceeded its rate limit
+	ErrStorage       = errors.New("storage error")       // The sample could not be written to the database
+)
+
+// SampleError is returned by ProcessSample when a sample fails parsing,
+// validation or storage. It matches Kind, which is ErrInvalidSample or
+// ErrStorage, as well as the underlying cause.
+type SampleError struct {
+	Kind   error
+	Reason string // Reason written to the error log
+	Err    error  // Underlying cause
+}
+
+func (e *SampleError) Error() string {
+	return e.Kind.Error() + ": " + e.Reason
+}
+
+func (e *SampleError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// RateLimitError is returned by ProcessSample when a sample is rejected by
+// the rate limiter. It matches ErrRateLimited.
+type RateLimitError struct {
+	CustomerID string
+	RetryAfter time.Duration // Time until the customer's next sample would be allowed
+
+	// Limit and Window are the limit the sample exceeded, as a number of
+	// requests per window, if the rate limiter reports it.
+	Limit  int
+	Window time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Window == 0 {
+		return ErrRateLimited.Error()
+	}
+	return fmt.Sprintf("%v: %d per %s", ErrRateLimited, e.Limit, formatWindow(e.Window))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// formatWindow formats a window without zero minutes and seconds, as 1h
+// rather than 1h0m0s.
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Reason returns the reason ProcessSample wrote to the error log when it
+// returned err.
+func Reason(err error) string {
+	var sampleErr *SampleError
+	if errors.As(err, &sampleErr) {
+		return sampleErr.Reason
+	}
+	return err.Error()
+}
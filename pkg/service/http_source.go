@@ -0,0 +1,184 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	maxFetchBackoff = 5 * time.Minute // Longest wait between retries of a failing endpoint, unless PollInterval is longer
+	emptyPageWait   = time.Second     // Wait after an empty page claiming more pages, if PollInterval is zero
+)
+
+// errInvalidSourceURL is returned for a source URL that cannot be parsed,
+// which retrying does not fix.
+var errInvalidSourceURL = errors.New("invalid source URL")
+
+// HTTPSourceConfig configures a source that pulls pages of samples from a
+// remote endpoint.
+type HTTPSourceConfig struct {
+	URL          string        // Endpoint returning pages of samples
+	CursorParam  string        // Query parameter carrying the cursor, "cursor" if empty
+	PollInterval time.Duration // Wait before polling again once caught up; zero stops instead
+	Client       *http.Client  // HTTP client to use, http.DefaultClient if nil
+}
+
+// page is the response body expected from the endpoint. The endpoint is
+// requested with the nextCursor of the previous page and sets hasMore
+// while further pages are immediately available.
+type page struct {
+	Samples    []json.RawMessage `json:"samples"`
+	NextCursor string            `json:"nextCursor"`
+	HasMore    bool              `json:"hasMore"`
+}
+
+// httpSource is a Source that polls a remote endpoint for pages of samples.
+type httpSource struct {
+	ctx     context.Context
+	cfg     HTTPSourceConfig
+	mapping FieldMapping
+	cursor  string // Cursor to request the next page with
+	pageNum int    // Number of pages fetched so far
+	samples []json.RawMessage
+	index   int  // Index of the next sample in samples
+	hasMore bool // Whether the last page said more pages are available
+	fetched bool // Whether at least one page has been fetched
+
+	// backoff is the wait before retrying after the last failed fetch
+	backoff time.Duration
+}
+
+// NewHTTPSource creates a source that pulls samples from cfg.URL page by
+// page. Once the endpoint reports no further pages, the source either ends
+// or, if cfg.PollInterval is set, keeps polling from the last cursor until
+// ctx is cancelled. When polling, failed requests are logged and retried
+// with exponential backoff rather than ending the source. Samples are
+// mapped with opts.FieldMapping; the other decode options do not apply to
+// JSON pages.
+func NewHTTPSource(ctx context.Context, cfg HTTPSourceConfig, opts DecodeOptions) Source {
+	if cfg.CursorParam == "" {
+		cfg.CursorParam = "cursor"
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &httpSource{ctx: ctx, cfg: cfg, mapping: opts.FieldMapping}
+}
+
+// Next returns the next sample, fetching further pages as needed. It
+// returns io.EOF once the endpoint is exhausted and polling is disabled, or
+// when ctx is cancelled.
+func (h *httpSource) Next() (Record, error) {
+	for h.index >= len(h.samples) {
+		switch {
+		case h.fetched && !h.hasMore:
+			if h.cfg.PollInterval == 0 {
+				return Record{}, io.EOF
+			}
+			if !h.wait(h.cfg.PollInterval) {
+				return Record{}, io.EOF
+			}
+		case h.fetched && len(h.samples) == 0:
+			// An empty page claiming more pages must not be refetched in
+			// a tight loop
+			if !h.wait(cmp.Or(h.cfg.PollInterval, emptyPageWait)) {
+				return Record{}, io.EOF
+			}
+		}
+
+		err := h.fetch()
+		if h.ctx.Err() != nil {
+			return Record{}, io.EOF
+		}
+		if err == nil {
+			h.backoff = 0
+			continue
+		}
+		if h.cfg.PollInterval == 0 || errors.Is(err, errInvalidSourceURL) {
+			return Record{}, err
+		}
+		h.backoff = max(min(h.backoff*2, maxFetchBackoff), h.cfg.PollInterval)
+		log.Printf("Error pulling samples from %s, retrying in %v: %v\n", h.cfg.URL, h.backoff, err)
+		if !h.wait(h.backoff) {
+			return Record{}, io.EOF
+		}
+	}
+
+	index := h.index
+	h.index++
+	position := fmt.Sprintf("page %d index %d", h.pageNum, index)
+
+	cs, err := h.mapping.decodeRecord(h.samples[index])
+	if err != nil {
+		return Record{}, &RecordError{Position: position, Err: err}
+	}
+	return Record{Sample: cs, Position: position}, nil
+}
+
+// fetch requests the page at the current cursor.
+func (h *httpSource) fetch() error {
+	u, err := url.Parse(h.cfg.URL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidSourceURL, err)
+	}
+	if h.cursor != "" {
+		q := u.Query()
+		q.Set(h.cfg.CursorParam, h.cursor)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching samples: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error fetching samples: unexpected status %s", resp.Status)
+	}
+
+	var p page
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return fmt.Errorf("error decoding page: %v", err)
+	}
+
+	h.pageNum++
+	h.samples = p.Samples
+	h.index = 0
+	h.hasMore = p.HasMore
+	h.fetched = true
+	if p.NextCursor != "" {
+		h.cursor = p.NextCursor
+	}
+	return nil
+}
+
+// wait waits for d, and reports false if ctx was cancelled first.
+func (h *httpSource) wait(d time.Duration) bool {
+	select {
+	case <-h.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Close implements Source. Polling stops when the context passed to
+// NewHTTPSource is cancelled.
+func (h *httpSource) Close() error {
+	return nil
+}
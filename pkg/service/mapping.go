@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// sampleFields lists the JSON names of the CustomSample fields that input
+// records are mapped onto.
+var sampleFields = []string{"customerId", "email", "name", "createdAt", "updatedAt"}
+
+// attributesKey is the record key holding explicit extra attributes.
+const attributesKey = "attributes"
+
+// FieldRule describes where the value of one CustomSample field comes from.
+type FieldRule struct {
+	// Paths are tried in order and the first one present in the record is
+	// used. A path is either a top-level key or a dotted path into nested
+	// objects, such as "client.id".
+	Paths []string `json:"paths"`
+
+	// Default is used when none of the paths is present.
+	Default string `json:"default,omitempty"`
+}
+
+// FieldMapping maps input records of any schema onto CustomSample fields.
+// It is keyed by CustomSample field name. Fields without a rule are read
+// from the key of the same name, so the zero mapping accepts the native
+// {"customerId", "email", "name", "createdAt", "updatedAt"} schema.
+//
+// Top-level keys that no rule refers to are kept as the sample's
+// attributes, as are the entries of an "attributes" object.
+//
+// For example, records shaped like {"id", "client_id", "email"} are
+// accepted with:
+//
+//	FieldMapping{"customerId": {Paths: []string{"client_id"}}}
+type FieldMapping map[string]FieldRule
+
+// LoadFieldMapping reads a field mapping from a JSON file of the form
+//
+//	{"customerId": {"paths": ["client_id", "client.id"]}, "name": {"default": "Unknown"}}
+func LoadFieldMapping(path string) (FieldMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading field mapping: %v", err)
+	}
+
+	var m FieldMapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error decoding field mapping: %v", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks that every rule targets a known CustomSample field.
+func (m FieldMapping) Validate() error {
+	for field := range m {
+		if canonicalField(field) != field {
+			return fmt.Errorf("field mapping: unknown sample field %q", field)
+		}
+	}
+	return nil
+}
+
+// readsAny reports whether any of fields is read from one of keys, the
+// top-level keys of a record.
+func (m FieldMapping) readsAny(keys []string, fields ...string) bool {
+	for _, field := range fields {
+		paths := m[field].Paths
+		if len(paths) == 0 {
+			paths = []string{field}
+		}
+		for _, path := range paths {
+			if slices.Contains(keys, path) || slices.Contains(keys, strings.SplitN(path, ".", 2)[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeRecord decodes a single JSON object and maps it onto a CustomSample.
+func (m FieldMapping) decodeRecord(data []byte) (CustomSample, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Keep numeric values such as epoch timestamps verbatim
+
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return CustomSample{}, err
+	}
+	return m.apply(raw)
+}
+
+// apply builds a CustomSample from a decoded record. Numbers and booleans
+// are converted to their text; objects and arrays are rejected.
+func (m FieldMapping) apply(raw map[string]interface{}) (CustomSample, error) {
+	values := make(map[string]string, len(sampleFields))
+	known := map[string]bool{attributesKey: true} // Top-level keys referred to by a rule
+	for _, field := range sampleFields {
+		rule, ok := m[field]
+		if !ok || len(rule.Paths) == 0 {
+			rule.Paths = []string{field}
+		}
+
+		value := rule.Default
+		found := false
+		for _, path := range rule.Paths {
+			known[path] = true
+			known[strings.SplitN(path, ".", 2)[0]] = true
+			if found {
+				continue
+			}
+
+			v, ok := lookup(raw, path)
+			if !ok {
+				continue
+			}
+			s, err := scalarString(v)
+			if err != nil {
+				return CustomSample{}, fmt.Errorf("%s: %v", path, err)
+			}
+			value, found = s, true
+		}
+		values[field] = value
+	}
+
+	attributes := make(map[string]interface{})
+	if explicit, ok := raw[attributesKey].(map[string]interface{}); ok {
+		for key, v := range explicit {
+			attributes[key] = plainJSON(v)
+		}
+	} else if v, ok := raw[attributesKey]; ok && v != nil {
+		attributes[attributesKey] = plainJSON(v)
+	}
+	for key, v := range raw {
+		if !known[key] {
+			attributes[key] = plainJSON(v)
+		}
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+
+	return CustomSample{
+		CustomerID: values["customerId"],
+		Email:      values["email"],
+		Name:       values["name"],
+		CreatedAt:  values["createdAt"],
+		UpdatedAt:  values["updatedAt"],
+		Attributes: attributes,
+	}, nil
+}
+
+// plainJSON converts the json.Number values in a decoded JSON value to
+// int64 or float64, so that attributes are stored as numbers.
+func plainJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = plainJSON(elem)
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = plainJSON(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// lookup returns the non-null value at path in raw. A key containing dots
+// takes precedence over the nested path it could also denote.
+func lookup(raw map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := raw[path]; ok {
+		return v, v != nil
+	}
+
+	var current interface{} = raw
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// scalarString returns the text of a decoded JSON scalar.
+func scalarString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expected a string, number or boolean, got %T", v)
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/validator"
+)
+
+func TestFieldMappingApply(t *testing.T) {
+	mapping := FieldMapping{
+		"customerId": {Paths: []string{"client_id", "client.id"}},
+		"name":       {Paths: []string{"profile.fullName"}, Default: "Unknown"},
+		"createdAt":  {Paths: []string{"created_at"}},
+	}
+
+	tests := []struct {
+		name    string
+		record  string
+		want    CustomSample
+		wantErr bool
+	}{
+		{
+			name:   "aliases and defaults",
+			record: `{"id": "record-001", "client_id": "client-A", "email": "user@example.com", "created_at": 1711454400}`,
+			want: CustomSample{
+				CustomerID: "client-A", Email: "user@example.com", Name: "Unknown", CreatedAt: "1711454400",
+				Attributes: map[string]interface{}{"id": "record-001"},
+			},
+		},
+		{
+			name:   "nested paths",
+			record: `{"client": {"id": "client-B"}, "profile": {"fullName": "Jane Smith"}, "email": "jane@example.com"}`,
+			want:   CustomSample{CustomerID: "client-B", Email: "jane@example.com", Name: "Jane Smith"},
+		},
+		{
+			name:   "null falls through to the next path",
+			record: `{"client_id": null, "client": {"id": 42}}`,
+			want:   CustomSample{CustomerID: "42", Name: "Unknown"},
+		},
+		{
+			name:    "object where a value is expected",
+			record:  `{"client_id": {"id": "client-C"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			record:  `["client-D"]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapping.decodeRecord([]byte(tt.record))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldMappingAttributes(t *testing.T) {
+	record := `{
+		"customerId": "1", "email": "a@example.com", "updatedAt": "2024-03-26T12:00:00Z",
+		"plan": "pro", "seats": 12, "ratio": 0.5, "tags": ["b2b", 3],
+		"attributes": {"region": "eu", "score": 7}
+	}`
+
+	got, err := FieldMapping{}.decodeRecord([]byte(record))
+	if err != nil {
+		t.Fatalf("decodeRecord() error = %v", err)
+	}
+	if got.UpdatedAt != "2024-03-26T12:00:00Z" {
+		t.Errorf("Expected updatedAt to be mapped, got %q", got.UpdatedAt)
+	}
+
+	// Unknown fields and explicit attributes are kept, with numbers as numbers
+	want := map[string]interface{}{
+		"plan":   "pro",
+		"seats":  int64(12),
+		"ratio":  0.5,
+		"tags":   []interface{}{"b2b", int64(3)},
+		"region": "eu",
+		"score":  int64(7),
+	}
+	if !reflect.DeepEqual(got.Attributes, want) {
+		t.Errorf("Attributes = %#v, want %#v", got.Attributes, want)
+	}
+}
+
+func TestAttributePolicyFilter(t *testing.T) {
+	attributes := map[string]interface{}{"plan": "pro", "seats": 12, "ssn": "123-45-6789"}
+
+	tests := []struct {
+		name   string
+		policy AttributePolicy
+		want   []string
+	}{
+		{"keep all", AttributePolicy{}, []string{"plan", "seats", "ssn"}},
+		{"allow list", AttributePolicy{Allow: []string{"plan", "seats"}}, []string{"plan", "seats"}},
+		{"deny list", AttributePolicy{Deny: []string{"ssn"}}, []string{"plan", "seats"}},
+		{"deny wins", AttributePolicy{Allow: []string{"ssn"}, Deny: []string{"ssn"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.filter(attributes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("filter() = %v, want keys %v", got, tt.want)
+			}
+			for _, key := range tt.want {
+				if _, ok := got[key]; !ok {
+					t.Errorf("filter() is missing %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFieldMapping(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`{"customerId": {"paths": ["client_id"]}, "name": {"default": "Unknown"}}`), 0644)
+	mapping, err := LoadFieldMapping(valid)
+	if err != nil {
+		t.Fatalf("LoadFieldMapping() error = %v", err)
+	}
+	if got := mapping["customerId"].Paths; len(got) != 1 || got[0] != "client_id" {
+		t.Errorf("Expected customerId paths [client_id], got %v", got)
+	}
+
+	unknown := filepath.Join(dir, "unknown.json")
+	os.WriteFile(unknown, []byte(`{"clientId": {"paths": ["client_id"]}}`), 0644)
+	if _, err := LoadFieldMapping(unknown); err == nil {
+		t.Error("Expected error for unknown sample field")
+	}
+}
+
+func TestProcessSamplesFileREADMESchema(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewRateLimiter(5), mockDB,
+		WithFieldMapping(FieldMapping{
+			"customerId": {Paths: []string{"client_id"}},
+			"name":       {Default: "Unknown"},
+			"createdAt":  {Default: time.Now().Format(time.RFC3339)},
+		}))
+
+	// Records shaped like the README example, as a bare top-level array
+	content := `[
+		{"id": "record-001", "client_id": "client-A", "email": "user@example.com"},
+		{"id": "record-002", "client_id": "client-B", "email": "bad-email"}
+	]`
+	filePath := createTestFile(t, "samples-*.json", content)
+	defer os.Remove(filePath)
+
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil {
+		t.Fatalf("ProcessSamplesFile() error = %v", err)
+	}
+	if result.SuccessCount != 1 || result.ErrorCount != 1 {
+		t.Errorf("Expected 1 success and 1 error, got %+v", result)
+	}
+	if got := mockDB.samples["client-A"]; got.Email != "user@example.com" || got.Name != "Unknown" {
+		t.Errorf("Unexpected sample for client-A: %+v", got)
+	}
+	if strings.Contains(string(mustReadFile(t, "error.log")), `"customerId": ""`) {
+		t.Error("Expected every error to carry the mapped customer ID")
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return data
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ndjsonReader decodes samples from line-delimited JSON, one CustomSample
+// per line. Only the current line is held in memory, so arbitrarily large
+// exports can be streamed through the service.
+type ndjsonReader struct {
+	r       *bufio.Reader
+	mapping FieldMapping
+	line    int // Number of the line most recently read (1-based)
+}
+
+// newNDJSONReader creates a reader that decodes samples from r line by line.
+func newNDJSONReader(r io.Reader, mapping FieldMapping) *ndjsonReader {
+	return &ndjsonReader{r: bufio.NewReader(r), mapping: mapping}
+}
+
+// Next returns the record on the next non-blank line. It returns io.EOF once
+// the input is exhausted and a *RecordError for a line that is not a valid
+// sample, in which case the caller may keep reading.
+func (n *ndjsonReader) Next() (Record, error) {
+	for {
+		raw, err := n.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return Record{}, fmt.Errorf("error reading file: %v", err)
+		}
+		if len(raw) == 0 && err == io.EOF {
+			return Record{}, io.EOF
+		}
+		n.line++
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+
+		cs, err := n.mapping.decodeRecord(raw)
+		if err != nil {
+			return Record{}, &RecordError{Position: fmt.Sprintf("line %d", n.line), Err: err}
+		}
+		return Record{Sample: cs, Position: fmt.Sprintf("line %d", n.line)}, nil
+	}
+}
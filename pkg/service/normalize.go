@@ -0,0 +1,91 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization selects the cleansing applied to samples before they are
+// validated. The zero value leaves samples unchanged.
+type Normalization struct {
+	TrimSpace            bool // Trims surrounding whitespace from every field
+	LowercaseEmailDomain bool // Lowercases the part of the email after the @
+	NFCNames             bool // Applies Unicode NFC normalization to names
+	CollapseWhitespace   bool // Replaces runs of whitespace in names with a single space
+}
+
+// DefaultNormalization enables every transformation.
+var DefaultNormalization = Normalization{
+	TrimSpace:            true,
+	LowercaseEmailDomain: true,
+	NFCNames:             true,
+	CollapseWhitespace:   true,
+}
+
+// normalize applies the enabled transformations to cs in place. It returns
+// the original value of each field it changed, keyed by field name, or nil
+// if nothing changed.
+func (n Normalization) normalize(cs *CustomSample) map[string]string {
+	var originals map[string]string
+	apply := func(field string, value *string, transform func(string) string) {
+		if normalized := transform(*value); normalized != *value {
+			if originals == nil {
+				originals = make(map[string]string)
+			}
+			if _, ok := originals[field]; !ok {
+				originals[field] = *value
+			}
+			*value = normalized
+		}
+	}
+
+	if n.TrimSpace {
+		apply("customerId", &cs.CustomerID, strings.TrimSpace)
+		apply("email", &cs.Email, strings.TrimSpace)
+		apply("name", &cs.Name, strings.TrimSpace)
+		apply("createdAt", &cs.CreatedAt, strings.TrimSpace)
+		apply("updatedAt", &cs.UpdatedAt, strings.TrimSpace)
+	}
+	if n.LowercaseEmailDomain {
+		apply("email", &cs.Email, lowercaseDomain)
+	}
+	if n.NFCNames {
+		apply("name", &cs.Name, norm.NFC.String)
+	}
+	if n.CollapseWhitespace {
+		apply("name", &cs.Name, collapseWhitespace)
+	}
+	return originals
+}
+
+// lowercaseDomain lowercases the domain of an email address. The local part
+// is left alone, as it may be case-sensitive.
+func lowercaseDomain(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	return email[:at+1] + strings.ToLower(email[at+1:])
+}
+
+// collapseWhitespace replaces each run of whitespace in s with a single
+// space. Leading and trailing whitespace is left to TrimSpace.
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	inSpace := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
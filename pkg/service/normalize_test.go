@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/validator"
+)
+
+func TestNormalization(t *testing.T) {
+	decomposed := "Jose\u0301" // "José" with a combining acute accent
+
+	tests := []struct {
+		name          string
+		normalization Normalization
+		in            CustomSample
+		want          CustomSample
+		wantOriginals map[string]string
+	}{
+		{
+			name:          "Disabled",
+			in:            CustomSample{Email: " John@Example.COM ", Name: decomposed},
+			want:          CustomSample{Email: " John@Example.COM ", Name: decomposed},
+			wantOriginals: nil,
+		},
+		{
+			name:          "Trim",
+			normalization: Normalization{TrimSpace: true},
+			in:            CustomSample{CustomerID: " 1", Email: "john@example.com\t", CreatedAt: "2024-03-26T12:00:00Z "},
+			want:          CustomSample{CustomerID: "1", Email: "john@example.com", CreatedAt: "2024-03-26T12:00:00Z"},
+			wantOriginals: map[string]string{"customerId": " 1", "email": "john@example.com\t", "createdAt": "2024-03-26T12:00:00Z "},
+		},
+		{
+			name:          "Lowercase email domain",
+			normalization: Normalization{LowercaseEmailDomain: true},
+			in:            CustomSample{Email: "John@Example.COM"},
+			want:          CustomSample{Email: "John@example.com"},
+			wantOriginals: map[string]string{"email": "John@Example.COM"},
+		},
+		{
+			name:          "NFC names",
+			normalization: Normalization{NFCNames: true},
+			in:            CustomSample{Name: decomposed},
+			want:          CustomSample{Name: "Jos\u00e9"},
+			wantOriginals: map[string]string{"name": decomposed},
+		},
+		{
+			name:          "Collapse whitespace",
+			normalization: Normalization{CollapseWhitespace: true},
+			in:            CustomSample{Name: "John \t  Doe"},
+			want:          CustomSample{Name: "John Doe"},
+			wantOriginals: map[string]string{"name": "John \t  Doe"},
+		},
+		{
+			name:          "All, keeping the first original",
+			normalization: DefaultNormalization,
+			in:            CustomSample{Email: " John@Example.COM", Name: "  John   Doe "},
+			want:          CustomSample{Email: "John@example.com", Name: "John Doe"},
+			wantOriginals: map[string]string{"email": " John@Example.COM", "name": "  John   Doe "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			originals := tt.normalization.normalize(&got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalize() sample = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(originals, tt.wantOriginals) {
+				t.Errorf("normalize() originals = %v, want %v", originals, tt.wantOriginals)
+			}
+		})
+	}
+}
+
+func TestProcessSampleNormalization(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewRateLimiter(5), mockDB,
+		WithNormalization(DefaultNormalization))
+
+	// A sample that is valid once normalized is stored normalized
+	err := service.ProcessSample(CustomSample{CustomerID: "1", Name: " John  Doe ", Email: "john@Example.com ", CreatedAt: "2024-03-26T12:00:00Z"})
+	if err != nil {
+		t.Fatalf("Failed to process sample: %v", err)
+	}
+	if got := mockDB.samples["1"]; got.Email != "john@example.com" || got.Name != "John Doe" {
+		t.Errorf("Expected normalized sample, got %+v", got)
+	}
+
+	// A rejected sample logs the original values
+	service.ProcessSample(CustomSample{CustomerID: "2", Name: " ", Email: "bad@Example.com", CreatedAt: "2024-03-26T12:00:00Z"})
+	data, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	var entry struct {
+		Originals map[string]string `json:"originals"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Failed to decode error.log entry: %v", err)
+	}
+	if want := map[string]string{"name": " ", "email": "bad@Example.com"}; !reflect.DeepEqual(entry.Originals, want) {
+		t.Errorf("Expected originals %v in error.log, got %v", want, entry.Originals)
+	}
+}
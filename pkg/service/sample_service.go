@@ -1,96 +1,271 @@
 package service
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
+	"errors"
+	"io"
+	"slices"
 	"time"
 
 	"gohighlevel/pkg/db"
-	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/interfaces"
 	"gohighlevel/pkg/types"
-	"gohighlevel/pkg/validator"
 )
 
 // SampleService orchestrates the processing of samples by coordinating
 // between the validator, rate limiter, and database components.
 type SampleService struct {
-	validator   *validator.Validator
-	rateLimiter *ratelimiter.RateLimiter
-	db          db.Database
+	validator     interfaces.Validator   // Writes the error log
+	validators    []interfaces.Validator // Default validator chain
+	profiles      ValidationProfiles     // Validator chains of individual customers
+	rateLimiter   interfaces.RateLimiter
+	db            db.Database
+	decodeOptions DecodeOptions   // Used for the sources the service opens itself
+	timeParser    TimeParser      // Parses the timestamps of incoming samples
+	attributes    AttributePolicy // Filters the extra attributes stored with samples
+	normalization Normalization   // Cleansing applied to samples before validation
+}
+
+// Option configures optional behaviour of a SampleService.
+type Option func(*SampleService)
+
+// WithColumnMapping sets the header-to-field mapping used when reading
+// CSV and TSV files.
+func WithColumnMapping(mapping ColumnMapping) Option {
+	return func(s *SampleService) {
+		s.decodeOptions.ColumnMapping = mapping
+	}
+}
+
+// WithFieldMapping sets the mapping of input records onto sample fields
+// used when reading files, so that upstream schemas other than the native
+// one can be ingested without preprocessing.
+func WithFieldMapping(mapping FieldMapping) Option {
+	return func(s *SampleService) {
+		s.decodeOptions.FieldMapping = mapping
+	}
+}
+
+// WithTimeParser sets how sample timestamps are parsed. By default only
+// RFC 3339 is accepted.
+func WithTimeParser(p TimeParser) Option {
+	return func(s *SampleService) {
+		s.timeParser = p
+	}
+}
+
+// WithAttributePolicy sets which extra input fields are stored under a
+// sample's attributes. By default all of them are.
+func WithAttributePolicy(p AttributePolicy) Option {
+	return func(s *SampleService) {
+		s.attributes = p
+	}
+}
+
+// WithNormalization sets the cleansing applied to samples before they are
+// validated. By default samples are validated as received.
+func WithNormalization(n Normalization) Option {
+	return func(s *SampleService) {
+		s.normalization = n
+	}
+}
+
+// WithValidators sets the chain of validators samples are checked by, in
+// order. By default samples are checked by the service's validator only.
+func WithValidators(chain ...interfaces.Validator) Option {
+	return func(s *SampleService) {
+		s.validators = chain
+	}
+}
+
+// WithValidationProfiles checks the samples of the customers assigned to a
+// profile with the profile's validator chain instead of the service's
+// validator. The other validators set with WithValidators still apply, in
+// their place around it.
+func WithValidationProfiles(p ValidationProfiles) Option {
+	return func(s *SampleService) {
+		s.profiles = p
+	}
 }
 
 // NewSampleService creates a new sample service with the required dependencies.
-func NewSampleService(v *validator.Validator, r *ratelimiter.RateLimiter, db db.Database) *SampleService {
-	return &SampleService{
+// The validator writes the error log and, unless WithValidators is given,
+// validates the samples.
+func NewSampleService(v interfaces.Validator, r interfaces.RateLimiter, db db.Database, opts ...Option) *SampleService {
+	s := &SampleService{
 		validator:   v,
+		validators:  []interfaces.Validator{v},
 		rateLimiter: r,
 		db:          db,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.profiles = s.profiles.within(s.validators, v)
+	return s
 }
 
 // CustomSample is used for JSON decoding with custom time parsing.
 // It matches the structure of samples in the JSON file.
 type CustomSample struct {
-	CustomerID string `json:"customerId"`
-	Email      string `json:"email"`
-	Name       string `json:"name"`
-	CreatedAt  string `json:"createdAt"`
+	CustomerID string                 `json:"customerId"`
+	Email      string                 `json:"email"`
+	Name       string                 `json:"name"`
+	CreatedAt  string                 `json:"createdAt"`
+	UpdatedAt  string                 `json:"updatedAt,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // Extra input fields
+}
+
+// AttributePolicy selects which extra input fields are kept as attributes.
+type AttributePolicy struct {
+	Allow []string // If set, only these attributes are kept
+	Deny  []string // These attributes are always dropped
+}
+
+// filter returns the attributes permitted by the policy, or nil if none are.
+func (p AttributePolicy) filter(attributes map[string]interface{}) map[string]interface{} {
+	kept := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		if (len(p.Allow) == 0 || slices.Contains(p.Allow, key)) && !slices.Contains(p.Deny, key) {
+			kept[key] = value
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// DecodeSample decodes a single JSON record using the service's field
+// mapping. Fields the mapping does not know are kept as attributes.
+func (s *SampleService) DecodeSample(data []byte) (CustomSample, error) {
+	return s.decodeOptions.FieldMapping.decodeRecord(data)
 }
 
 // ProcessResult holds the statistics of sample processing.
 type ProcessResult struct {
-	SuccessCount int // Number of successfully processed samples
-	ErrorCount   int // Number of samples that failed processing
+	SuccessCount int `json:"successCount"` // Number of successfully processed samples
+	ErrorCount   int `json:"errorCount"`   // Number of samples that failed processing
+	WarningCount int `json:"warningCount"` // Number of successfully processed samples with warnings
 }
 
-// ProcessSamplesFile reads and processes samples from a JSON file.
-// It handles file operations and JSON decoding, then delegates the
-// actual processing to ProcessSamples.
-func (s *SampleService) ProcessSamplesFile(filepath string) (ProcessResult, error) {
-	jsonFile, err := os.Open(filepath)
-	if err != nil {
-		return ProcessResult{}, fmt.Errorf("error opening file: %v", err)
+// add counts the outcome of processing one sample.
+func (r *ProcessResult) add(warnings types.ValidationErrors, err error) {
+	switch {
+	case err != nil:
+		r.ErrorCount++
+	case len(warnings) > 0:
+		r.SuccessCount++
+		r.WarningCount++
+	default:
+		r.SuccessCount++
 	}
-	defer jsonFile.Close()
+}
 
-	var data struct {
-		Samples []CustomSample `json:"samples"`
-	}
-	if err := json.NewDecoder(jsonFile).Decode(&data); err != nil {
-		return ProcessResult{}, fmt.Errorf("error decoding JSON: %v", err)
+// ProcessSamplesFile reads and processes samples from a file.
+// Files ending in .jsonl or .ndjson are read as line-delimited JSON, one
+// sample per line; .csv and .tsv files are read as rows mapped onto sample
+// fields by their header; anything else is read as a {"samples": [...]}
+// JSON document. Gzip and zstd compressed files are decompressed on the
+// fly, so "samples.jsonl.gz" is read as line-delimited JSON. In every case
+// samples are streamed and processed as they are decoded rather than
+// loaded into memory up front.
+func (s *SampleService) ProcessSamplesFile(path string) (ProcessResult, error) {
+	src, err := NewFileSource(path, s.decodeOptions)
+	if err != nil {
+		return ProcessResult{}, err
 	}
+	defer src.Close()
 
-	return s.ProcessSamples(data.Samples)
+	return s.ProcessSource(src)
+}
+
+// ProcessSource processes samples as they are read from src. Records that
+// fail to decode are logged, counted as errors and skipped; any other read
+// error stops processing and is returned along with the statistics
+// gathered so far. Errors and warnings are logged with the position of
+// their record in src. The caller remains responsible for closing src.
+func (s *SampleService) ProcessSource(src Source) (ProcessResult, error) {
+	var result ProcessResult
+	for {
+		record, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			s.validator.WriteErrorLog("", recordErr.Error())
+			result.ErrorCount++
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		warnings, _, err := s.processSample(record.Sample, record.Position)
+		result.add(warnings, err)
+	}
+	return result, nil
 }
 
 // ProcessSamples processes a batch of samples and returns the processing statistics.
-// It tracks successful processing and uses the validator to count errors.
+// Counts cover this batch only, so a long-lived service can report on each
+// input separately.
 func (s *SampleService) ProcessSamples(samples []CustomSample) (ProcessResult, error) {
-	successCount := 0
+	var result ProcessResult
 	for _, cs := range samples {
-		if err := s.ProcessSample(cs); err == nil {
-			successCount++
-		}
+		warnings, _, err := s.processSample(cs, "")
+		result.add(warnings, err)
 	}
-	return ProcessResult{
-		SuccessCount: successCount,
-		ErrorCount:   s.validator.GetErrorCount(),
-	}, nil
+	return result, nil
 }
 
 // ProcessSample processes a single sample through the following steps:
-// 1. Parses the creation timestamp
-// 2. Validates the sample data
-// 3. Checks rate limiting
-// 4. Inserts the sample into the database
-// Returns error if any step fails, nil on success.
+// 1. Normalizes the input fields
+// 2. Parses the timestamps and normalizes them to UTC
+// 3. Corrects and validates the sample data with the customer's validator
+// chain
+// 4. Checks rate limiting
+// 5. Inserts the sample into the database, with any warnings
+// 6. Logs the warnings the sample was accepted with
+// Returns error if any step fails, nil on success. The error matches
+// ErrInvalidSample, ErrRateLimited or ErrStorage depending on the step.
+// ProcessSample is safe for concurrent use.
 func (s *SampleService) ProcessSample(cs CustomSample) error {
+	_, _, err := s.processSample(cs, "")
+	return err
+}
+
+// ProcessSampleRemaining processes a sample like ProcessSample, and also
+// returns how many more samples its customer may send at the sample's event
+// time, as reported by the rate limiter when it checked the sample. It
+// returns -1 if the sample was rejected before reaching the rate limiter.
+func (s *SampleService) ProcessSampleRemaining(cs CustomSample) (int, error) {
+	_, remaining, err := s.processSample(cs, "")
+	return remaining, err
+}
+
+// processSample processes a sample like ProcessSample and also returns the
+// warnings it was accepted with and the remaining requests of its customer.
+// position is the location of the sample in its input, if any, and is
+// included in the error and warnings logs.
+func (s *SampleService) processSample(cs CustomSample, position string) (types.ValidationErrors, int, error) {
+	// Normalize input, keeping the original values for the error log
+	originals := s.normalization.normalize(&cs)
+
 	// Parse time
-	createdAt, err := time.Parse(time.RFC3339, cs.CreatedAt)
+	createdAt, err := s.timeParser.Parse(cs.CreatedAt)
 	if err != nil {
-		s.validator.WriteErrorLog(cs.CustomerID, "invalid date format: "+cs.CreatedAt)
-		return err
+		s.logError(types.Sample{CustomerID: cs.CustomerID, Originals: originals, Position: position}, err.Error())
+		return nil, -1, &SampleError{Kind: ErrInvalidSample, Reason: err.Error(), Err: err}
+	}
+
+	var updatedAt time.Time
+	if cs.UpdatedAt != "" {
+		if updatedAt, err = s.timeParser.Parse(cs.UpdatedAt); err != nil {
+			s.logError(types.Sample{CustomerID: cs.CustomerID, Originals: originals, Position: position}, err.Error())
+			return nil, -1, &SampleError{Kind: ErrInvalidSample, Reason: err.Error(), Err: err}
+		}
 	}
 
 	sample := types.Sample{
@@ -98,24 +273,57 @@ func (s *SampleService) ProcessSample(cs CustomSample) error {
 		Email:      cs.Email,
 		Name:       cs.Name,
 		CreatedAt:  createdAt,
+		UpdatedAt:  updatedAt,
+		Attributes: s.attributes.filter(cs.Attributes),
+		Originals:  originals,
+		Position:   position,
 	}
 
-	// Validate sample
-	if err := s.validator.ValidateSample(sample); err != nil {
-		return err // ValidateSample already logs the error
+	// Correct and validate sample, keeping the checks it was flagged by
+	warnings, err := s.validate(&sample)
+	if err != nil {
+		// The validators already log the error
+		return nil, -1, &SampleError{Kind: ErrInvalidSample, Reason: err.Error(), Err: err}
 	}
+	sample.Warnings = warnings
 
 	// Check rate limit
-	if !s.rateLimiter.IsAllowed(sample.CustomerID, sample.CreatedAt) {
-		s.validator.WriteErrorLog(sample.CustomerID, "rate limit exceeded")
-		return fmt.Errorf("rate limit exceeded")
+	allowed, remaining := s.allow(sample.CustomerID, sample.CreatedAt)
+	if !allowed {
+		err := &RateLimitError{
+			CustomerID: sample.CustomerID,
+			RetryAfter: s.rateLimiter.RetryAfter(sample.CustomerID, sample.CreatedAt),
+		}
+		if wl, ok := s.rateLimiter.(interfaces.WindowedRateLimiter); ok {
+			err.Limit, err.Window = wl.ExceededLimit(sample.CustomerID, sample.CreatedAt)
+		}
+		s.logError(sample, err.Error())
+		return nil, remaining, err
 	}
 
 	// Insert valid sample
 	if err := s.db.InsertSample(sample); err != nil {
-		s.validator.WriteErrorLog(sample.CustomerID, "failed to insert: "+err.Error())
-		return err
+		reason := "failed to insert: " + err.Error()
+		s.logError(sample, reason)
+		return nil, remaining, &SampleError{Kind: ErrStorage, Reason: reason, Err: err}
+	}
+
+	// Log the warnings of the accepted sample
+	if len(warnings) > 0 {
+		s.logWarnings(sample)
 	}
 
-	return nil
+	return warnings, remaining, nil
+}
+
+// allow checks a sample of the customer at at with the rate limiter, and
+// returns how many more samples the customer may send at at. Rate limiters
+// implementing interfaces.AtomicRateLimiter do both in one step; for others
+// the remaining requests are those reported for the current time.
+func (s *SampleService) allow(customerID string, at time.Time) (bool, int) {
+	if rl, ok := s.rateLimiter.(interfaces.AtomicRateLimiter); ok {
+		return rl.Allow(customerID, at)
+	}
+	allowed := s.rateLimiter.IsAllowed(customerID, at)
+	return allowed, s.rateLimiter.GetRemainingRequests(customerID)
 }
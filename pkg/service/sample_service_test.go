@@ -1,14 +1,21 @@
 package service
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"gohighlevel/pkg/ratelimiter"
 	"gohighlevel/pkg/types"
 	"gohighlevel/pkg/validator"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // MockDatabase implements the Database interface for testing
@@ -270,3 +277,375 @@ func TestProcessSamplesTimeWindow(t *testing.T) {
 		t.Errorf("Expected 0 errors, got %d", result.ErrorCount)
 	}
 }
+
+func TestProcessSamplesFileNDJSON(t *testing.T) {
+	service, mockDB, cleanup := setupTestService(t)
+	defer cleanup()
+
+	now := time.Now().Format(time.RFC3339)
+	lines := []string{
+		`{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "` + now + `"}`,
+		`{"customerId": "2", "name": "Broken", "email": `,
+		``,
+		`{"customerId": "3", "name": "Jane Smith", "email": "jane@example.com", "createdAt": "` + now + `"}`,
+	}
+
+	filePath := createTestFile(t, "samples-*.jsonl", strings.Join(lines, "\n"))
+	defer os.Remove(filePath)
+
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil {
+		t.Fatalf("ProcessSamplesFile() error = %v", err)
+	}
+
+	// The malformed line should be skipped, not abort the run
+	if result.SuccessCount != 2 {
+		t.Errorf("Expected 2 successful samples, got %d", result.SuccessCount)
+	}
+	if result.ErrorCount != 1 {
+		t.Errorf("Expected 1 error, got %d", result.ErrorCount)
+	}
+	if _, ok := mockDB.samples["3"]; !ok {
+		t.Error("Expected sample after the malformed line to be inserted")
+	}
+
+	// The error log should point at the offending line
+	errorLog, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(errorLog), "line 2") {
+		t.Errorf("Expected error.log to reference line 2, got %s", errorLog)
+	}
+}
+
+// Helper function to write raw content to a temporary file
+func createTestFile(t *testing.T, pattern, content string) string {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	file.Close()
+	return file.Name()
+}
+
+func TestProcessSamplesFileBadElement(t *testing.T) {
+	service, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	now := time.Now().Format(time.RFC3339)
+	content := `{
+		"source": {"name": "export"},
+		"samples": [
+			{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "` + now + `"},
+			{"customerId": {"id": "2"}, "name": "Wrong Type", "email": "wrong@example.com", "createdAt": "` + now + `"},
+			{"customerId": "3", "name": "Jane Smith", "email": "jane@example.com", "createdAt": "` + now + `"}
+		]
+	}`
+	filePath := createTestFile(t, "samples-*.json", content)
+	defer os.Remove(filePath)
+
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil {
+		t.Fatalf("ProcessSamplesFile() error = %v", err)
+	}
+
+	// A single bad element should not fail the whole file
+	if result.SuccessCount != 2 {
+		t.Errorf("Expected 2 successful samples, got %d", result.SuccessCount)
+	}
+	if result.ErrorCount != 1 {
+		t.Errorf("Expected 1 error, got %d", result.ErrorCount)
+	}
+
+	errorLog, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(errorLog), "index 1") {
+		t.Errorf("Expected error.log to reference index 1, got %s", errorLog)
+	}
+}
+
+func TestProcessSamplesFileMalformedJSON(t *testing.T) {
+	service, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	now := time.Now().Format(time.RFC3339)
+	content := `{"samples": [
+		{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "` + now + `"},
+		{"customerId": "2", "name": `
+	filePath := createTestFile(t, "samples-*.json", content)
+	defer os.Remove(filePath)
+
+	// Samples decoded before the syntax error are still processed
+	result, err := service.ProcessSamplesFile(filePath)
+	if err == nil || !strings.Contains(err.Error(), "error decoding JSON") {
+		t.Errorf("Expected JSON decoding error, got %v", err)
+	}
+	if result.SuccessCount != 1 {
+		t.Errorf("Expected 1 successful sample, got %d", result.SuccessCount)
+	}
+}
+
+func TestProcessSamplesFileCSV(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	v := validator.NewValidator(mockDB)
+	r := ratelimiter.NewRateLimiter(5)
+	service := NewSampleService(v, r, mockDB, WithColumnMapping(ColumnMapping{
+		"Customer ID": "customerId",
+		"E-mail":      "email",
+		"Full Name":   "name",
+		"Signup Date": "createdAt",
+	}))
+
+	now := time.Now().Format(time.RFC3339)
+	content := "Customer ID,Full Name,E-mail,Signup Date,Notes\n" +
+		"1,John Doe,john@example.com," + now + ",first\n" +
+		"2,Short Row\n" +
+		"3,\"Smith, Jane\",jane@example.com," + now + ",\n" +
+		"4,Bad Email,not-an-email," + now + ",\n"
+	filePath := createTestFile(t, "samples-*.csv", content)
+	defer os.Remove(filePath)
+
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil {
+		t.Fatalf("ProcessSamplesFile() error = %v", err)
+	}
+	if result.SuccessCount != 2 {
+		t.Errorf("Expected 2 successful samples, got %d", result.SuccessCount)
+	}
+	if result.ErrorCount != 2 {
+		t.Errorf("Expected 2 errors, got %d", result.ErrorCount)
+	}
+	if got := mockDB.samples["3"].Name; got != "Smith, Jane" {
+		t.Errorf("Name mismatch: got %q, want %q", got, "Smith, Jane")
+	}
+
+	errorLog, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(errorLog), "row 3") {
+		t.Errorf("Expected error.log to reference row 3, got %s", errorLog)
+	}
+	// Rows that parse but fail validation are logged with their row too
+	if !strings.Contains(string(errorLog), `"position": "row 5"`) {
+		t.Errorf("Expected error.log to reference row 5, got %s", errorLog)
+	}
+}
+
+func TestProcessSamplesFileTSV(t *testing.T) {
+	service, mockDB, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// Without a mapping, headers named after the sample fields are used
+	now := time.Now().Format(time.RFC3339)
+	content := "customerId\temail\tname\tcreatedAt\n" +
+		"1\tjohn@example.com\tJohn Doe\t" + now + "\n"
+	filePath := createTestFile(t, "samples-*.tsv", content)
+	defer os.Remove(filePath)
+
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil {
+		t.Fatalf("ProcessSamplesFile() error = %v", err)
+	}
+	if result.SuccessCount != 1 {
+		t.Errorf("Expected 1 successful sample, got %d", result.SuccessCount)
+	}
+	if got := mockDB.samples["1"].Email; got != "john@example.com" {
+		t.Errorf("Email mismatch: got %q, want %q", got, "john@example.com")
+	}
+}
+
+func TestProcessSamplesFileCSVHeader(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	content := "client_id,mail,full_name,signed_up\n" +
+		"1,john@example.com,John Doe," + now + "\n"
+	filePath := createTestFile(t, "samples-*.csv", content)
+	defer os.Remove(filePath)
+
+	// A header none of whose columns feed a sample field fails fast
+	service, _, cleanup := setupTestService(t)
+	defer cleanup()
+	if _, err := service.ProcessSamplesFile(filePath); err == nil || !strings.Contains(err.Error(), "no column maps to a sample field") {
+		t.Errorf("ProcessSamplesFile() error = %v, want a header error", err)
+	}
+
+	// The field mapping can make the same header usable
+	mockDB := NewMockDatabase()
+	service = NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewRateLimiter(5), mockDB, WithFieldMapping(FieldMapping{
+		"customerId": {Paths: []string{"client_id"}},
+		"email":      {Paths: []string{"mail"}},
+		"name":       {Paths: []string{"full_name"}},
+		"createdAt":  {Paths: []string{"signed_up"}},
+	}))
+	result, err := service.ProcessSamplesFile(filePath)
+	if err != nil || result.SuccessCount != 1 {
+		t.Errorf("ProcessSamplesFile() = %+v, %v, want 1 successful sample", result, err)
+	}
+}
+
+func TestProcessSamplesFileCompressed(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	ndjson := `{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "` + now + `"}` + "\n" +
+		`{"customerId": "2", "name": "Jane Smith", "email": "jane@example.com", "createdAt": "` + now + `"}` + "\n"
+	envelope := `{"samples": [{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "` + now + `"}]}`
+
+	gzipped := func(content string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(content))
+		zw.Close()
+		return buf.Bytes()
+	}
+	zstded := func(content string) []byte {
+		zw, _ := zstd.NewWriter(nil)
+		defer zw.Close()
+		return zw.EncodeAll([]byte(content), nil)
+	}
+
+	tests := []struct {
+		name        string
+		pattern     string
+		content     []byte
+		wantSuccess int
+	}{
+		{"gzip by extension", "samples-*.jsonl.gz", gzipped(ndjson), 2},
+		{"zstd by extension", "samples-*.jsonl.zst", zstded(ndjson), 2},
+		{"gzip by magic bytes", "samples-*.json", gzipped(envelope), 1},
+		{"zstd by magic bytes", "samples-*.json", zstded(envelope), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, cleanup := setupTestService(t)
+			defer cleanup()
+
+			filePath := createTestFile(t, tt.pattern, string(tt.content))
+			defer os.Remove(filePath)
+
+			result, err := service.ProcessSamplesFile(filePath)
+			if err != nil {
+				t.Fatalf("ProcessSamplesFile() error = %v", err)
+			}
+			if result.SuccessCount != tt.wantSuccess {
+				t.Errorf("Expected %d successful samples, got %d", tt.wantSuccess, result.SuccessCount)
+			}
+		})
+	}
+}
+
+func TestProcessSampleTimeParser(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewRateLimiter(5), mockDB,
+		WithTimeParser(TimeParser{Layouts: CommonTimeLayouts, ParseEpoch: true}))
+
+	samples := []CustomSample{
+		{CustomerID: "epoch", Name: "Epoch", Email: "epoch@example.com", CreatedAt: "1711454400000"},
+		{CustomerID: "offset", Name: "Offset", Email: "offset@example.com", CreatedAt: "2024-03-26T17:30:00+05:30"},
+		{CustomerID: "date", Name: "Date", Email: "date@example.com", CreatedAt: "2024-03-26"},
+	}
+	result, _ := service.ProcessSamples(samples)
+	if result.SuccessCount != 3 {
+		t.Fatalf("Expected 3 successful samples, got %d", result.SuccessCount)
+	}
+
+	// Timestamps are stored in UTC
+	noon := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"epoch", "offset"} {
+		if got := mockDB.samples[id].CreatedAt; !got.Equal(noon) || got.Location() != time.UTC {
+			t.Errorf("%s: expected CreatedAt %v in UTC, got %v", id, noon, got)
+		}
+	}
+}
+
+func TestProcessSampleUpdatedAtAndAttributes(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewRateLimiter(5), mockDB,
+		WithAttributePolicy(AttributePolicy{Deny: []string{"ssn"}}))
+
+	cs, err := service.DecodeSample([]byte(`{"customerId": "1", "name": "John", "email": "john@example.com",
+		"createdAt": "2024-03-26T12:00:00Z", "updatedAt": "2024-03-27T12:00:00Z", "plan": "pro", "ssn": "123"}`))
+	if err != nil {
+		t.Fatalf("Failed to decode sample: %v", err)
+	}
+	if err := service.ProcessSample(cs); err != nil {
+		t.Fatalf("Failed to process sample: %v", err)
+	}
+
+	got := mockDB.samples["1"]
+	if want := time.Date(2024, 3, 27, 12, 0, 0, 0, time.UTC); !got.UpdatedAt.Equal(want) {
+		t.Errorf("Expected UpdatedAt %v, got %v", want, got.UpdatedAt)
+	}
+	if want := map[string]interface{}{"plan": "pro"}; !reflect.DeepEqual(got.Attributes, want) {
+		t.Errorf("Expected attributes %v, got %v", want, got.Attributes)
+	}
+
+	cs.CustomerID, cs.UpdatedAt = "2", "yesterday"
+	if err := service.ProcessSample(cs); !errors.Is(err, ErrInvalidSample) {
+		t.Errorf("Expected ErrInvalidSample for a bad updatedAt, got %v", err)
+	}
+}
+
+func TestProcessSampleRateLimitWindow(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	hourly, err := ratelimiter.ParseLimit("3/1h")
+	if err != nil {
+		t.Fatalf("ParseLimit() error = %v", err)
+	}
+	tiers, err := ratelimiter.NewTiers(ratelimiter.TierConfig{Default: []ratelimiter.Limit{{Requests: 2}, hourly}})
+	if err != nil {
+		t.Fatalf("NewTiers() error = %v", err)
+	}
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB), ratelimiter.NewTieredRateLimiter(tiers), mockDB)
+
+	base := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+	var lastErr error
+	for i, offset := range []time.Duration{0, 0, 2 * time.Minute, 3 * time.Minute} {
+		lastErr = service.ProcessSample(CustomSample{
+			CustomerID: "window-test",
+			Name:       "Window Test",
+			Email:      "window@example.com",
+			CreatedAt:  base.Add(offset).Format(time.RFC3339),
+		})
+		if i < 3 && lastErr != nil {
+			t.Fatalf("Sample %d: ProcessSample() error = %v", i+1, lastErr)
+		}
+	}
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(lastErr, &rateLimitErr) || rateLimitErr.Limit != 3 || rateLimitErr.Window != time.Hour {
+		t.Fatalf("Expected the 1h window to be exceeded, got %v", lastErr)
+	}
+	data, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(data), `"reason": "rate limit exceeded: 3 per 1h"`) {
+		t.Errorf("Expected the exceeded window in error.log, got:\n%s", data)
+	}
+}
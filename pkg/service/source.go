@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Record is a sample read from a Source together with its position in
+// that source.
+type Record struct {
+	Sample   CustomSample
+	Position string // Location of the record in the source, e.g. "line 12"
+}
+
+// Source yields sample records one at a time. New kinds of input can be
+// ingested by implementing Source and passing it to ProcessSource.
+type Source interface {
+	// Next returns the next record. It returns io.EOF once the source is
+	// exhausted and a *RecordError for a record that could not be decoded,
+	// in which case the caller may keep reading. Any other error is fatal.
+	Next() (Record, error)
+
+	// Close releases the resources held by the source.
+	Close() error
+}
+
+// RecordError describes a single input record that could not be decoded.
+// It does not abort processing: the record is written to the error log and
+// skipped.
+type RecordError struct {
+	Position string // Location of the record in the input, e.g. "line 12"
+	Err      error  // Underlying decoding error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("malformed record at %s: %v", e.Position, e.Err)
+}
+
+// Format identifies how samples are encoded in a stream.
+type Format string
+
+const (
+	FormatJSON   Format = "json"   // A {"samples": [...]} JSON document
+	FormatNDJSON Format = "ndjson" // Line-delimited JSON, one sample per line
+	FormatCSV    Format = "csv"    // Comma-separated rows with a header
+	FormatTSV    Format = "tsv"    // Tab-separated rows with a header
+)
+
+// DecodeOptions controls how sources decode raw input into samples.
+type DecodeOptions struct {
+	Format        Format        // Input format; JSON, or derived from the file name, if empty
+	ColumnMapping ColumnMapping // Header mapping for CSV and TSV input
+	FieldMapping  FieldMapping  // Mapping of input records onto sample fields
+}
+
+// FormatFromPath returns the format implied by the extension of path,
+// ignoring any compression suffix. Unknown extensions are read as JSON.
+func FormatFromPath(path string) Format {
+	switch formatExt(path) {
+	case ".jsonl", ".ndjson":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	case ".tsv":
+		return FormatTSV
+	default:
+		return FormatJSON
+	}
+}
+
+// recordDecoder decodes records from an underlying stream.
+type recordDecoder interface {
+	Next() (Record, error)
+}
+
+// streamSource is a Source that decodes records from a byte stream.
+type streamSource struct {
+	recordDecoder
+	closers []io.Closer // Closed in order by Close
+}
+
+func (s *streamSource) Close() error {
+	var firstErr error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewReaderSource creates a source that decodes samples in opts.Format
+// from r. Gzip and zstd compressed input is detected and decompressed on
+// the fly. Closing the source does not close r.
+func NewReaderSource(r io.Reader, opts DecodeOptions) (Source, error) {
+	return newStreamSource("", r, opts)
+}
+
+// NewFileSource creates a source that reads samples from the file at path.
+// Unless opts.Format is set, the format is chosen from the file extension
+// as described by FormatFromPath. Compression is detected from the content
+// or a .gz/.zst extension.
+func NewFileSource(path string, opts DecodeOptions) (Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %v", err)
+	}
+
+	if opts.Format == "" {
+		opts.Format = FormatFromPath(path)
+	}
+	src, err := newStreamSource(path, file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	src.closers = append(src.closers, file)
+	return src, nil
+}
+
+// NewStdinSource creates a source that reads samples in opts.Format from
+// standard input.
+func NewStdinSource(opts DecodeOptions) (Source, error) {
+	return NewReaderSource(os.Stdin, opts)
+}
+
+// newStreamSource decompresses r if needed and picks the decoder for
+// opts.Format. path is only used to detect compression from its extension.
+func newStreamSource(path string, r io.Reader, opts DecodeOptions) (*streamSource, error) {
+	content, err := decompress(path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var dec recordDecoder
+	switch opts.Format {
+	case FormatNDJSON:
+		dec = newNDJSONReader(content, opts.FieldMapping)
+	case FormatCSV:
+		dec = newCSVReader(content, ',', opts)
+	case FormatTSV:
+		dec = newCSVReader(content, '\t', opts)
+	case FormatJSON, "":
+		dec = newEnvelopeReader(content, opts.FieldMapping)
+	default:
+		content.Close()
+		return nil, fmt.Errorf("unsupported format: %q", opts.Format)
+	}
+	return &streamSource{recordDecoder: dec, closers: []io.Closer{content}}, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Helper function to drain a source into its records and record errors
+func readAll(t *testing.T, src Source) ([]Record, []*RecordError) {
+	var records []Record
+	var recordErrs []*RecordError
+	for {
+		record, err := src.Next()
+		if err == io.EOF {
+			return records, recordErrs
+		}
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			recordErrs = append(recordErrs, recordErr)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestReaderSourcePositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    Format
+		content   string
+		positions []string
+	}{
+		{
+			name:      "ndjson",
+			format:    FormatNDJSON,
+			content:   `{"customerId": "1"}` + "\n\n" + `{"customerId": "2"}`,
+			positions: []string{"line 1", "line 3"},
+		},
+		{
+			name:      "json",
+			format:    FormatJSON,
+			content:   `{"samples": [{"customerId": "1"}, {"customerId": "2"}]}`,
+			positions: []string{"index 0", "index 1"},
+		},
+		{
+			name:      "csv",
+			format:    FormatCSV,
+			content:   "customerId\n1\n2\n",
+			positions: []string{"row 2", "row 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := NewReaderSource(strings.NewReader(tt.content), DecodeOptions{Format: tt.format})
+			if err != nil {
+				t.Fatalf("NewReaderSource() error = %v", err)
+			}
+			defer src.Close()
+
+			records, _ := readAll(t, src)
+			if len(records) != len(tt.positions) {
+				t.Fatalf("Expected %d records, got %d", len(tt.positions), len(records))
+			}
+			for i, record := range records {
+				if record.Position != tt.positions[i] {
+					t.Errorf("Record %d: expected position %q, got %q", i, tt.positions[i], record.Position)
+				}
+				if want := fmt.Sprint(i + 1); record.Sample.CustomerID != want {
+					t.Errorf("Record %d: expected customer %q, got %q", i, want, record.Sample.CustomerID)
+				}
+			}
+		})
+	}
+}
+
+func TestReaderSourceUnsupportedFormat(t *testing.T) {
+	if _, err := NewReaderSource(strings.NewReader(""), DecodeOptions{Format: "xml"}); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	// Two pages, the second one with a malformed sample
+	pages := map[string]string{
+		"":   `{"samples": [{"customerId": "1"}, {"customerId": "2"}], "nextCursor": "p2", "hasMore": true}`,
+		"p2": `{"samples": ["bad", {"customerId": "4"}], "nextCursor": "p3", "hasMore": false}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Query().Get("cursor")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	src := NewHTTPSource(context.Background(), HTTPSourceConfig{URL: ts.URL}, DecodeOptions{})
+	defer src.Close()
+
+	records, recordErrs := readAll(t, src)
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if got := records[2].Position; got != "page 2 index 1" {
+		t.Errorf("Expected position %q, got %q", "page 2 index 1", got)
+	}
+	if len(recordErrs) != 1 || recordErrs[0].Position != "page 2 index 0" {
+		t.Errorf("Expected one record error at page 2 index 0, got %v", recordErrs)
+	}
+}
+
+func TestHTTPSourcePolling(t *testing.T) {
+	// The endpoint has one sample at first and another one on a later poll
+	polls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"samples": [{"customerId": "1"}], "nextCursor": "c1"}`))
+		case "c1":
+			if polls < 3 {
+				w.Write([]byte(`{"samples": []}`))
+				return
+			}
+			w.Write([]byte(`{"samples": [{"customerId": "2"}], "nextCursor": "c2"}`))
+		default:
+			w.Write([]byte(`{"samples": []}`))
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	src := NewHTTPSource(ctx, HTTPSourceConfig{URL: ts.URL, PollInterval: time.Millisecond}, DecodeOptions{})
+	defer src.Close()
+
+	for _, want := range []string{"1", "2"} {
+		record, err := src.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if record.Sample.CustomerID != want {
+			t.Errorf("Expected customer %q, got %q", want, record.Sample.CustomerID)
+		}
+	}
+
+	// Cancelling the context ends the source
+	cancel()
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after cancel, got %v", err)
+	}
+}
+
+func TestHTTPSourceRetries(t *testing.T) {
+	// The endpoint fails twice before serving its only page
+	var polls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) <= 2 {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"samples": [{"customerId": "1"}], "nextCursor": "c1"}`))
+	}))
+	defer ts.Close()
+
+	// Without polling, a failure ends the source
+	src := NewHTTPSource(context.Background(), HTTPSourceConfig{URL: ts.URL}, DecodeOptions{})
+	if _, err := src.Next(); err == nil || err == io.EOF {
+		t.Errorf("Expected the 502 to be returned, got %v", err)
+	}
+
+	// When polling, failures are retried
+	src = NewHTTPSource(context.Background(), HTTPSourceConfig{URL: ts.URL, PollInterval: time.Millisecond}, DecodeOptions{})
+	record, err := src.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if n := polls.Load(); record.Sample.CustomerID != "1" || n != 3 {
+		t.Errorf("Expected customer 1 after 3 polls, got %q after %d", record.Sample.CustomerID, n)
+	}
+}
+
+func TestHTTPSourceWaitsAfterEmptyPage(t *testing.T) {
+	// The endpoint keeps claiming more pages without returning any samples
+	var polls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		w.Write([]byte(`{"samples": [], "hasMore": true}`))
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	src := NewHTTPSource(ctx, HTTPSourceConfig{URL: ts.URL, PollInterval: 20 * time.Millisecond}, DecodeOptions{})
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF once the context is done, got %v", err)
+	}
+	if n := polls.Load(); n > 6 {
+		t.Errorf("Expected the empty pages to be polled every 20ms, got %d polls in 100ms", n)
+	}
+}
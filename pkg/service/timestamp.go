@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochMillisThreshold separates epoch seconds from epoch milliseconds.
+// As seconds it is in the year 5138, as milliseconds in 1973.
+const epochMillisThreshold = 1e11
+
+// TimeParser parses sample timestamps. The zero value accepts RFC 3339
+// only. All parsed times are normalized to UTC.
+type TimeParser struct {
+	// Layouts are tried in order. RFC 3339 is used if empty.
+	Layouts []string
+
+	// Location is used for layouts without a zone offset, such as
+	// "2006-01-02 15:04:05". UTC is used if nil.
+	Location *time.Location
+
+	// ParseEpoch accepts numeric values as Unix epoch seconds, or epoch
+	// milliseconds when they are too large to be plausible seconds.
+	ParseEpoch bool
+}
+
+// CommonTimeLayouts is a broad set of layouts seen in upstream feeds. It is
+// selected with the "common" layout by ExpandTimeLayouts.
+var CommonTimeLayouts = []string{
+	time.RFC3339Nano, // Also accepts RFC 3339 without fractional seconds
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Parse parses value with the first matching layout, or failing that as an
+// epoch timestamp if enabled, and returns it in UTC. Layouts are tried first
+// so that numeric layouts such as "20060102" are not read as epoch seconds.
+func (p TimeParser) Parse(value string) (time.Time, error) {
+	trimmed := strings.TrimSpace(value)
+
+	layouts := p.Layouts
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, trimmed, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	if p.ParseEpoch {
+		if t, ok := parseEpoch(trimmed); ok {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", value)
+}
+
+// ExpandTimeLayouts returns layouts with every "common" entry replaced by
+// CommonTimeLayouts.
+func ExpandTimeLayouts(layouts []string) []string {
+	var expanded []string
+	for _, layout := range layouts {
+		if layout == "common" {
+			expanded = append(expanded, CommonTimeLayouts...)
+			continue
+		}
+		expanded = append(expanded, layout)
+	}
+	return expanded
+}
+
+// parseEpoch parses value as epoch seconds or milliseconds, with an
+// optional fractional part.
+func parseEpoch(value string) (time.Time, bool) {
+	if value == "" || strings.ContainsAny(value, "eE") {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return time.Time{}, false
+	}
+
+	if math.Abs(n) >= epochMillisThreshold {
+		return time.UnixMilli(int64(n)).UTC(), true
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), true
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimeParserDefault(t *testing.T) {
+	var p TimeParser
+
+	got, err := p.Parse("2024-03-26T17:30:00+05:30")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("Parse() = %v, want %v in UTC", got, want)
+	}
+
+	// Only RFC 3339 is accepted by default
+	for _, value := range []string{"1711454400", "2024-03-26 12:00:00", "2024-03-26"} {
+		if _, err := p.Parse(value); err == nil {
+			t.Errorf("Parse(%q) should fail with the default parser", value)
+		}
+	}
+}
+
+func TestTimeParserLayoutsBeforeEpoch(t *testing.T) {
+	p := TimeParser{Layouts: []string{"20060102"}, ParseEpoch: true}
+
+	got, err := p.Parse("20240101")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+
+	// Values no layout matches are still read as epoch timestamps
+	if got, err := p.Parse("1711454400"); err != nil || !got.Equal(time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Parse() = %v, %v, want epoch seconds", got, err)
+	}
+}
+
+func TestExpandTimeLayouts(t *testing.T) {
+	got := ExpandTimeLayouts([]string{"20060102", "common"})
+	want := append([]string{"20060102"}, CommonTimeLayouts...)
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandTimeLayouts() = %v, want %v", got, want)
+	}
+}
+
+func TestTimeParserConfigured(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	p := TimeParser{
+		Layouts:    CommonTimeLayouts,
+		Location:   kolkata,
+		ParseEpoch: true,
+	}
+	noon := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"1711454400", noon},
+		{"1711454400000", noon},
+		{"1711454400.5", noon.Add(500 * time.Millisecond)},
+		{"2024-03-26T12:00:00.123456789Z", noon.Add(123456789)},
+		{"2024-03-26T12:00:00Z", noon},
+		{"2024-03-26 17:30:00", noon},                                  // Configured zone
+		{"2024-03-26", time.Date(2024, 3, 25, 18, 30, 0, 0, time.UTC)}, // Midnight in the configured zone
+		{" 2024-03-26T12:00:00Z ", noon},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := p.Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("Parse() = %v, want %v in UTC", got, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "not-a-date", "1e10", "26/03/2024"} {
+		if _, err := p.Parse(value); err == nil {
+			t.Errorf("Parse(%q) should fail", value)
+		}
+	}
+}
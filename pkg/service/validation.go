@@ -0,0 +1,112 @@
+package service
+
+import (
+	"slices"
+
+	"gohighlevel/pkg/interfaces"
+	"gohighlevel/pkg/types"
+)
+
+// ValidationProfiles assigns customers to named validator chains, so that
+// some customers can be held to stricter rules than others.
+type ValidationProfiles struct {
+	Chains    map[string][]interfaces.Validator // Validator chains keyed by profile name
+	Customers map[string]string                 // Profile names keyed by customer ID
+}
+
+// within returns the profiles with each chain put in place of the built-in
+// validator in the default chain, so that the other validators of the
+// default chain still apply to every customer. If the default chain does
+// not include the built-in validator, the profile chains run ahead of it.
+func (p ValidationProfiles) within(defaults []interfaces.Validator, builtin interfaces.Validator) ValidationProfiles {
+	if len(p.Chains) == 0 {
+		return p
+	}
+	i := slices.Index(defaults, builtin)
+	chains := make(map[string][]interfaces.Validator, len(p.Chains))
+	for name, chain := range p.Chains {
+		if i < 0 {
+			chains[name] = slices.Concat(chain, defaults)
+		} else {
+			chains[name] = slices.Concat(defaults[:i], chain, defaults[i+1:])
+		}
+	}
+	return ValidationProfiles{Chains: chains, Customers: p.Customers}
+}
+
+// chain returns the validator chain of the customer's profile, if the
+// customer has one.
+func (p ValidationProfiles) chain(customerID string) ([]interfaces.Validator, bool) {
+	profile, ok := p.Customers[customerID]
+	if !ok {
+		return nil, false
+	}
+	chain, ok := p.Chains[profile]
+	return chain, ok
+}
+
+// validate checks sample with each validator of its customer's chain in
+// order, stopping at the first that rejects it. Each validator logs its
+// own errors. Validators implementing interfaces.Corrector first correct
+// the sample in place, and the warnings of validators implementing
+// interfaces.WarningValidator are collected.
+func (s *SampleService) validate(sample *types.Sample) (types.ValidationErrors, error) {
+	chain, ok := s.profiles.chain(sample.CustomerID)
+	if !ok {
+		chain = s.validators
+	}
+
+	for _, v := range chain {
+		if c, ok := v.(interfaces.Corrector); ok {
+			*sample = c.CorrectSample(*sample)
+		}
+	}
+
+	var warnings types.ValidationErrors
+	for _, v := range chain {
+		if wv, ok := v.(interfaces.WarningValidator); ok {
+			w, err := wv.Validate(*sample)
+			if err != nil {
+				return nil, err
+			}
+			warnings = append(warnings, w...)
+			continue
+		}
+		if err := v.ValidateSample(*sample); err != nil {
+			return nil, err
+		}
+	}
+	return warnings, nil
+}
+
+// sampleErrorLogger is implemented by validators that can log the input
+// values normalization changed along with an error.
+type sampleErrorLogger interface {
+	WriteSampleErrorLog(sample types.Sample, reason string) error
+}
+
+// logError writes an error for sample to the error log.
+func (s *SampleService) logError(sample types.Sample, reason string) {
+	if l, ok := s.validator.(sampleErrorLogger); ok {
+		l.WriteSampleErrorLog(sample, reason)
+		return
+	}
+	if sample.Position != "" {
+		reason = sample.Position + ": " + reason
+	}
+	s.validator.WriteErrorLog(sample.CustomerID, reason)
+}
+
+// warningLogger is implemented by validators that keep a log of the
+// samples accepted with warnings.
+type warningLogger interface {
+	WriteWarningLog(sample types.Sample) error
+}
+
+// logWarnings writes the warnings sample was accepted with to the warnings
+// log, if the service's validator keeps one.
+func (s *SampleService) logWarnings(sample types.Sample) {
+	if l, ok := s.validator.(warningLogger); ok {
+		l.WriteWarningLog(sample)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"gohighlevel/pkg/interfaces"
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/types"
+	"gohighlevel/pkg/validator"
+)
+
+// nameValidator rejects samples with a given name, standing in for a
+// custom check.
+type nameValidator struct {
+	name  string
+	calls int
+}
+
+func (v *nameValidator) ValidateSample(sample types.Sample) error {
+	v.calls++
+	if sample.Name == v.name {
+		return errors.New("name is not allowed")
+	}
+	return nil
+}
+
+func (v *nameValidator) WriteErrorLog(customerID, reason string) error { return nil }
+
+func TestValidatorChain(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	v := validator.NewValidator(mockDB)
+	custom := &nameValidator{name: "Robot"}
+	service := NewSampleService(v, ratelimiter.NewRateLimiter(5), mockDB, WithValidators(v, custom))
+
+	samples := []CustomSample{
+		{CustomerID: "1", Name: "John", Email: "john@example.com", CreatedAt: "2024-03-26T12:00:00Z"},
+		{CustomerID: "2", Name: "Robot", Email: "robot@example.com", CreatedAt: "2024-03-26T12:00:00Z"},
+		{CustomerID: "3", Name: "Robot", Email: "bad-email", CreatedAt: "2024-03-26T12:00:00Z"},
+	}
+	result, _ := service.ProcessSamples(samples)
+	if result.SuccessCount != 1 || result.ErrorCount != 2 {
+		t.Errorf("Expected 1 success and 2 errors, got %d and %d", result.SuccessCount, result.ErrorCount)
+	}
+
+	// The chain stops at the first validator that rejects a sample
+	if custom.calls != 2 {
+		t.Errorf("Expected the custom validator to be called 2 times, got %d", custom.calls)
+	}
+}
+
+func TestValidationProfiles(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	v := validator.NewValidator(mockDB)
+	rules, err := validator.CompileRules([]validator.Rule{{Name: "company-email", Field: "email", Pattern: `@acme\.com$`}})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+	strict := validator.NewValidator(mockDB, validator.WithRules(rules))
+
+	service := NewSampleService(v, ratelimiter.NewRateLimiter(5), mockDB, WithValidationProfiles(ValidationProfiles{
+		Chains:    map[string][]interfaces.Validator{"enterprise": {strict}},
+		Customers: map[string]string{"acme": "enterprise"},
+	}))
+
+	tests := []struct {
+		customerID string
+		email      string
+		wantErr    bool
+	}{
+		{"acme", "john@acme.com", false},
+		{"acme", "john@example.com", true},
+		{"other", "john@example.com", false},
+	}
+	for _, tt := range tests {
+		err := service.ProcessSample(CustomSample{CustomerID: tt.customerID, Name: "John", Email: tt.email, CreatedAt: "2024-03-26T12:00:00Z"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ProcessSample(%s, %s) error = %v, wantErr %v", tt.customerID, tt.email, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidationProfilesKeepCustomValidators(t *testing.T) {
+	if err := os.Remove("error.log"); err != nil && !os.IsNotExist(err) {
+		t.Fatalf("Failed to remove error.log: %v", err)
+	}
+	defer os.Remove("error.log")
+
+	mockDB := NewMockDatabase()
+	v := validator.NewValidator(mockDB)
+	rules, err := validator.CompileRules([]validator.Rule{{Name: "company-email", Field: "email", Pattern: `@acme\.com$`}})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+	strict := validator.NewValidator(mockDB, validator.WithRules(rules))
+	custom := &nameValidator{name: "Robot"}
+
+	// The profile replaces the built-in validator only, so the custom
+	// check still applies to customers with a profile
+	service := NewSampleService(v, ratelimiter.NewRateLimiter(5), mockDB,
+		WithValidators(v, custom),
+		WithValidationProfiles(ValidationProfiles{
+			Chains:    map[string][]interfaces.Validator{"enterprise": {strict}},
+			Customers: map[string]string{"acme": "enterprise"},
+		}),
+	)
+
+	tests := []struct {
+		customerID string
+		name       string
+		email      string
+		wantErr    bool
+	}{
+		{"acme", "John", "john@acme.com", false},
+		{"acme", "John", "john@example.com", true},
+		{"acme", "Robot", "robot@acme.com", true},
+		{"other", "Robot", "robot@example.com", true},
+	}
+	for _, tt := range tests {
+		err := service.ProcessSample(CustomSample{CustomerID: tt.customerID, Name: tt.name, Email: tt.email, CreatedAt: "2024-03-26T12:00:00Z"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ProcessSample(%s, %s, %s) error = %v, wantErr %v", tt.customerID, tt.name, tt.email, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProcessSamplesWarnings(t *testing.T) {
+	for _, name := range []string{"error.log", "warnings.log"} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			t.Fatalf("Failed to remove %s: %v", name, err)
+		}
+		defer os.Remove(name)
+	}
+
+	rules, err := validator.CompileRules([]validator.Rule{
+		{Name: "all-caps", Field: "name", Pattern: `\p{Ll}`, Severity: validator.SeverityWarning},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+	mockDB := NewMockDatabase()
+	service := NewSampleService(validator.NewValidator(mockDB, validator.WithRules(rules)), ratelimiter.NewRateLimiter(5), mockDB)
+
+	samples := []CustomSample{
+		{CustomerID: "1", Name: "John", Email: "john@example.com", CreatedAt: "2024-03-26T12:00:00Z"},
+		{CustomerID: "2", Name: "JOHN", Email: "john@example.com", CreatedAt: "2024-03-26T12:00:00Z"},
+		{CustomerID: "3", Name: "JOHN", Email: "bad-email", CreatedAt: "2024-03-26T12:00:00Z"},
+	}
+	result, _ := service.ProcessSamples(samples)
+	if want := (ProcessResult{SuccessCount: 2, ErrorCount: 1, WarningCount: 1}); result != want {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+
+	if warnings := mockDB.samples["2"].Warnings; len(warnings) != 1 || warnings[0].Rule != "all-caps" {
+		t.Errorf("Expected the all-caps warning to be stored, got %v", warnings)
+	}
+	data, err := os.ReadFile("warnings.log")
+	if err != nil {
+		t.Fatalf("Failed to read warnings.log: %v", err)
+	}
+	if !strings.Contains(string(data), `"rule": "all-caps"`) || strings.Contains(string(data), `"customerId": "3"`) {
+		t.Errorf("Expected only the accepted sample in warnings.log, got:\n%s", data)
+	}
+}
+
+func TestProcessSamplesAutoCorrect(t *testing.T) {
+	mockDB := NewMockDatabase()
+	v := validator.NewValidator(mockDB,
+		validator.WithDomainSuggester(validator.NewDomainSuggester()),
+		validator.WithEmailAutoCorrect(true),
+		validator.WithDomainResolver(func(string) bool { return false }),
+	)
+	service := NewSampleService(v, ratelimiter.NewRateLimiter(5), mockDB, WithNormalization(DefaultNormalization))
+
+	samples := []CustomSample{
+		{CustomerID: "1", Name: "Jane", Email: "Jane@GMIAL.com", CreatedAt: "2024-03-26T12:00:00Z"},
+	}
+	if result, _ := service.ProcessSamples(samples); result.SuccessCount != 1 || result.WarningCount != 0 {
+		t.Fatalf("Expected the corrected sample to be inserted without warnings, got %+v", result)
+	}
+
+	stored := mockDB.samples["1"]
+	want := types.Correction{Field: "email", Original: "Jane@gmial.com", Corrected: "Jane@gmail.com"}
+	if stored.Email != want.Corrected || len(stored.Corrections) != 1 || stored.Corrections[0] != want {
+		t.Errorf("Expected email %q with correction %+v, got %q with %+v", want.Corrected, want, stored.Email, stored.Corrections)
+	}
+	if stored.Originals["email"] != "Jane@GMIAL.com" {
+		t.Errorf("Expected the pre-normalization email in the originals, got %v", stored.Originals)
+	}
+}
@@ -0,0 +1,338 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gohighlevel/pkg/service"
+)
+
+const (
+	processedDir = "processed" // Subdirectory for files that were ingested
+	failedDir    = "failed"    // Subdirectory for files that could not be read
+	lockSuffix   = ".lock"     // Suffix of the lock file claiming an inbox file
+	resultSuffix = ".result.json"
+
+	// DefaultStaleLockTimeout is how long a lock may go without being
+	// refreshed before another worker takes it over.
+	DefaultStaleLockTimeout = 10 * time.Minute
+)
+
+// takeovers numbers the stale locks taken over by this process, to give
+// each a unique name while it is removed.
+var takeovers atomic.Int64
+
+// Watcher continuously ingests sample files dropped into an inbox directory.
+// Each file is claimed with a lock file so that several workers can share
+// the same inbox, processed through a SampleService, and then moved to the
+// processed/ or failed/ subdirectory together with its result. A worker
+// refreshes its locks while it holds them, so that the lock of a worker
+// that crashed or was killed can be recognised as stale and taken over.
+type Watcher struct {
+	dir          string
+	service      *service.SampleService
+	pollInterval time.Duration
+	staleLock    time.Duration // Age after which an unrefreshed lock is taken over
+}
+
+// Option configures optional behaviour of a Watcher.
+type Option func(*Watcher)
+
+// WithStaleLockTimeout sets how long a lock may go without being refreshed
+// before it is considered abandoned and taken over. The default is
+// DefaultStaleLockTimeout; zero only takes over the locks of exited
+// processes of this host.
+func WithStaleLockTimeout(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.staleLock = d
+	}
+}
+
+// FileResult is the outcome of ingesting a single file. It is written as
+// JSON next to the file once it has been moved out of the inbox.
+type FileResult struct {
+	File string `json:"file"`
+	service.ProcessResult
+	Error      string    `json:"error,omitempty"` // Set when the file could not be read to the end
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// NewWatcher creates a watcher for the inbox directory dir that checks for
+// new files every pollInterval.
+func NewWatcher(dir string, s *service.SampleService, pollInterval time.Duration, opts ...Option) *Watcher {
+	w := &Watcher{
+		dir:          dir,
+		service:      s,
+		pollInterval: pollInterval,
+		staleLock:    DefaultStaleLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run scans the inbox until ctx is cancelled. It only returns early if the
+// inbox cannot be prepared.
+func (w *Watcher) Run(ctx context.Context) error {
+	if err := w.prepare(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Scan(); err != nil {
+			log.Printf("Error scanning %s: %v\n", w.dir, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan ingests every file currently waiting in the inbox. Files that are
+// locked by another worker are left alone.
+func (w *Watcher) Scan() error {
+	if err := w.prepare(); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && isInboxFile(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names) // Oldest first for timestamped file names
+
+	for _, name := range names {
+		if err := w.ingest(name); err != nil {
+			log.Printf("Error ingesting %s: %v\n", name, err)
+		}
+	}
+	return nil
+}
+
+// prepare creates the processed/ and failed/ subdirectories of the inbox.
+func (w *Watcher) prepare() error {
+	for _, sub := range []string{processedDir, failedDir} {
+		if err := os.MkdirAll(filepath.Join(w.dir, sub), 0755); err != nil {
+			return fmt.Errorf("error creating %s directory: %v", sub, err)
+		}
+	}
+	return nil
+}
+
+// ingest claims, processes and moves a single inbox file. It returns nil
+// without doing anything if another worker already holds the file.
+func (w *Watcher) ingest(name string) error {
+	path := filepath.Join(w.dir, name)
+
+	unlock, err := lock(path, w.staleLock/3)
+	if errors.Is(err, os.ErrExist) && w.reclaim(path) {
+		unlock, err = lock(path, w.staleLock/3)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Another worker may have finished the file between listing and locking
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	result := FileResult{File: name, StartedAt: time.Now()}
+	processResult, err := w.service.ProcessSamplesFile(path)
+	result.ProcessResult = processResult
+	result.FinishedAt = time.Now()
+
+	destDir := processedDir
+	if err != nil {
+		result.Error = err.Error()
+		destDir = failedDir
+	}
+
+	dest := filepath.Join(w.dir, destDir, name)
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("error moving file to %s: %v", destDir, err)
+	}
+	return writeResult(dest+resultSuffix, result)
+}
+
+// lock claims path by exclusively creating its lock file, recording the
+// host and PID of the worker. It returns an error wrapping os.ErrExist if
+// the file is already claimed, and a function that releases the claim
+// otherwise. Until released, the modification time of the lock is
+// refreshed every refresh.
+func lock(path string, refresh time.Duration) (func(), error) {
+	lockPath := path + lockSuffix
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(f, "%s %d %s\n", hostname(), os.Getpid(), time.Now().Format(time.RFC3339))
+	f.Close()
+
+	done := make(chan struct{})
+	if refresh > 0 {
+		go func() {
+			ticker := time.NewTicker(refresh)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-ticker.C:
+					if err := os.Chtimes(lockPath, now, now); err != nil {
+						log.Printf("Warning: Failed to refresh lock %s: %v\n", lockPath, err)
+					}
+				}
+			}
+		}()
+	}
+
+	return func() {
+		close(done)
+		if err := os.Remove(lockPath); err != nil {
+			log.Printf("Warning: Failed to remove lock %s: %v\n", lockPath, err)
+		}
+	}, nil
+}
+
+// reclaim removes the lock of path if it is stale: either it has not been
+// refreshed for the stale lock timeout, or it was taken by a process of
+// this host that no longer runs. It reports whether the lock was removed.
+func (w *Watcher) reclaim(path string) bool {
+	lockPath := path + lockSuffix
+	info, reason := w.stale(lockPath)
+	if reason == "" {
+		return false
+	}
+	return takeOver(lockPath, info, reason)
+}
+
+// stale returns the lock file at lockPath and the reason it is stale, or
+// an empty reason if it is not.
+func (w *Watcher) stale(lockPath string) (os.FileInfo, string) {
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return nil, ""
+	}
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, ""
+	}
+
+	owner := strings.TrimSpace(string(data))
+	if age := time.Since(info.ModTime()); w.staleLock > 0 && age > w.staleLock {
+		return info, fmt.Sprintf("not refreshed for %v: %s", age.Round(time.Second), owner)
+	}
+	if host, pid, ok := lockOwner(owner); ok && host != "" && host == hostname() && !processRunning(pid) {
+		return info, fmt.Sprintf("process %d has exited: %s", pid, owner)
+	}
+	return nil, ""
+}
+
+// takeOver removes the lock file at lockPath that was found stale as info.
+// The lock is first renamed to a name unique to this worker, so that of
+// several workers finding the same stale lock only one removes it. If
+// another worker replaced the lock in the meantime, the renamed lock is not
+// the one found stale and is put back.
+func takeOver(lockPath string, info os.FileInfo, reason string) bool {
+	// The name ends in .tmp so that scans skip it
+	takenPath := fmt.Sprintf("%s.%s-%d-%d.tmp", lockPath, hostname(), os.Getpid(), takeovers.Add(1))
+	if err := os.Rename(lockPath, takenPath); err != nil {
+		return false
+	}
+	if taken, err := os.Stat(takenPath); err != nil || !os.SameFile(info, taken) || !taken.ModTime().Equal(info.ModTime()) {
+		// Link rather than rename back, so as not to replace a lock taken since
+		if err := os.Link(takenPath, lockPath); err != nil {
+			log.Printf("Warning: Failed to restore lock %s: %v\n", lockPath, err)
+		}
+		os.Remove(takenPath)
+		return false
+	}
+
+	log.Printf("Taking over stale lock %s (%s)\n", lockPath, reason)
+	return os.Remove(takenPath) == nil
+}
+
+// lockOwner parses the host and PID a lock file was written with.
+func lockOwner(content string) (host string, pid int, ok bool) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	pid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], pid, true
+}
+
+// hostname returns the name of this host, or "" if it is unknown.
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// processRunning reports whether a process with the given PID runs on this
+// host. It only reports false if the process is known not to exist.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// writeResult writes result as indented JSON to path.
+func writeResult(path string, result FileResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// isInboxFile reports whether name is a file waiting to be ingested rather
+// than a lock, a result, or a file that is still being written.
+func isInboxFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	for _, suffix := range []string{lockSuffix, resultSuffix, ".tmp", ".part"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/ratelimiter"
+	"gohighlevel/pkg/service"
+	"gohighlevel/pkg/types"
+	"gohighlevel/pkg/validator"
+)
+
+// mockDB implements the Database interface for testing
+type mockDB struct{}
+
+func (m *mockDB) Init() error                            { return nil }
+func (m *mockDB) Close()                                 {}
+func (m *mockDB) InsertSample(sample types.Sample) error { return nil }
+
+// Helper function to create a watcher on a temporary inbox
+func setupTestWatcher(t *testing.T) (*Watcher, string) {
+	t.Cleanup(func() { os.Remove("error.log") })
+
+	db := &mockDB{}
+	s := service.NewSampleService(validator.NewValidator(db), ratelimiter.NewRateLimiter(5), db)
+	dir := t.TempDir()
+	return NewWatcher(dir, s, time.Second), dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func readResult(t *testing.T, path string) FileResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read result %s: %v", path, err)
+	}
+	var result FileResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to decode result %s: %v", path, err)
+	}
+	return result
+}
+
+func TestWatcherScan(t *testing.T) {
+	w, dir := setupTestWatcher(t)
+
+	now := time.Now().Format(time.RFC3339)
+	writeFile(t, filepath.Join(dir, "good.jsonl"),
+		`{"customerId": "1", "name": "John Doe", "email": "john@example.com", "createdAt": "`+now+`"}`+"\n"+
+			`{"customerId": "2", "name": "Bad Email", "email": "bad", "createdAt": "`+now+`"}`+"\n")
+	writeFile(t, filepath.Join(dir, "bad.json"), "{ invalid json")
+	writeFile(t, filepath.Join(dir, "upload.json.part"), "")
+
+	if err := w.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	// Readable files go to processed/ with their result alongside
+	if _, err := os.Stat(filepath.Join(dir, processedDir, "good.jsonl")); err != nil {
+		t.Errorf("Expected good.jsonl in processed/: %v", err)
+	}
+	good := readResult(t, filepath.Join(dir, processedDir, "good.jsonl"+resultSuffix))
+	if good.SuccessCount != 1 || good.ErrorCount != 1 {
+		t.Errorf("Expected 1 success and 1 error, got %+v", good.ProcessResult)
+	}
+
+	// Unreadable files go to failed/
+	if _, err := os.Stat(filepath.Join(dir, failedDir, "bad.json")); err != nil {
+		t.Errorf("Expected bad.json in failed/: %v", err)
+	}
+	if bad := readResult(t, filepath.Join(dir, failedDir, "bad.json"+resultSuffix)); bad.Error == "" {
+		t.Error("Expected failed result to carry the error")
+	}
+
+	// Partial uploads are left alone and no locks remain
+	if _, err := os.Stat(filepath.Join(dir, "upload.json.part")); err != nil {
+		t.Errorf("Expected partial upload to stay in the inbox: %v", err)
+	}
+	if locks, _ := filepath.Glob(filepath.Join(dir, "*"+lockSuffix)); len(locks) != 0 {
+		t.Errorf("Expected no lock files, got %v", locks)
+	}
+}
+
+func TestWatcherSkipsLockedFiles(t *testing.T) {
+	w, dir := setupTestWatcher(t)
+
+	path := filepath.Join(dir, "samples.json")
+	writeFile(t, path, `{"samples": []}`)
+
+	// Simulate another worker holding the file
+	writeFile(t, path+lockSuffix, "")
+
+	if err := w.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected locked file to stay in the inbox: %v", err)
+	}
+
+	// Once released, the next scan picks it up
+	os.Remove(path + lockSuffix)
+	if err := w.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, processedDir, "samples.json")); err != nil {
+		t.Errorf("Expected samples.json in processed/: %v", err)
+	}
+}
+
+func TestWatcherReclaimsStaleLocks(t *testing.T) {
+	w, dir := setupTestWatcher(t)
+
+	// A worker on another host stopped refreshing its lock an hour ago
+	abandoned := filepath.Join(dir, "abandoned.json")
+	writeFile(t, abandoned, `{"samples": []}`)
+	writeFile(t, abandoned+lockSuffix, "other-host 12345 2024-03-26T12:00:00Z\n")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(abandoned+lockSuffix, old, old); err != nil {
+		t.Fatalf("Failed to age lock: %v", err)
+	}
+
+	// A worker on this host exited without removing its fresh lock
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("Failed to run a process to exit: %v", err)
+	}
+	crashed := filepath.Join(dir, "crashed.json")
+	writeFile(t, crashed, `{"samples": []}`)
+	writeFile(t, crashed+lockSuffix, fmt.Sprintf("%s %d %s\n", hostname(), exited.Process.Pid, time.Now().Format(time.RFC3339)))
+
+	// A worker on another host holds a fresh lock
+	held := filepath.Join(dir, "held.json")
+	writeFile(t, held, `{"samples": []}`)
+	writeFile(t, held+lockSuffix, "other-host 12345 "+time.Now().Format(time.RFC3339)+"\n")
+
+	if err := w.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	for _, name := range []string{"abandoned.json", "crashed.json"} {
+		if _, err := os.Stat(filepath.Join(dir, processedDir, name)); err != nil {
+			t.Errorf("Expected %s to be taken over and processed: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(held); err != nil {
+		t.Errorf("Expected the file with a fresh lock to stay in the inbox: %v", err)
+	}
+}
+
+func TestReclaimConcurrently(t *testing.T) {
+	w, dir := setupTestWatcher(t)
+	path := filepath.Join(dir, "samples.json")
+
+	for i := 0; i < 50; i++ {
+		writeFile(t, path+lockSuffix, "other-host 12345 2024-03-26T12:00:00Z\n")
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(path+lockSuffix, old, old); err != nil {
+			t.Fatalf("Failed to age lock: %v", err)
+		}
+
+		// Every worker finds the same stale lock and tries to take it over
+		var wg sync.WaitGroup
+		unlocks := make(chan func(), 4)
+		for j := 0; j < cap(unlocks); j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock, err := lock(path, 0)
+				if errors.Is(err, os.ErrExist) && w.reclaim(path) {
+					unlock, err = lock(path, 0)
+				}
+				if err == nil {
+					unlocks <- unlock
+				}
+			}()
+		}
+		wg.Wait()
+		close(unlocks)
+
+		if len(unlocks) != 1 {
+			t.Fatalf("Iteration %d: %d workers took the stale lock, want 1", i, len(unlocks))
+		}
+		(<-unlocks)()
+		if leftovers, _ := filepath.Glob(path + lockSuffix + "*"); len(leftovers) != 0 {
+			t.Fatalf("Iteration %d: lock files left behind: %v", i, leftovers)
+		}
+	}
+}
+
+func TestReclaimAfterTakeover(t *testing.T) {
+	w, dir := setupTestWatcher(t)
+	path := filepath.Join(dir, "samples.json")
+	writeFile(t, path+lockSuffix, "other-host 12345 2024-03-26T12:00:00Z\n")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+lockSuffix, old, old); err != nil {
+		t.Fatalf("Failed to age lock: %v", err)
+	}
+
+	// Worker B finds the lock stale, but worker A takes it over first
+	info, reason := w.stale(path + lockSuffix)
+	if reason == "" {
+		t.Fatal("Expected the lock to be stale")
+	}
+	if !w.reclaim(path) {
+		t.Fatal("Expected worker A to reclaim the stale lock")
+	}
+	unlock, err := lock(path, 0)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	defer unlock()
+
+	if takeOver(path+lockSuffix, info, reason) {
+		t.Error("Expected worker B not to take over the lock of worker A")
+	}
+	if _, err := lock(path, 0); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected the lock of worker A to be kept, got %v", err)
+	}
+	if leftovers, _ := filepath.Glob(path + lockSuffix + ".*"); len(leftovers) != 0 {
+		t.Errorf("Lock files left behind: %v", leftovers)
+	}
+}
+
+func TestLockRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.json")
+	unlock, err := lock(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	defer unlock()
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path+lockSuffix, old, old); err != nil {
+		t.Fatalf("Failed to age lock: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if info, err := os.Stat(path + lockSuffix); err != nil || info.ModTime().Before(time.Now().Add(-time.Minute)) {
+		t.Errorf("Expected a held lock to be refreshed, got %v, %v", info, err)
+	}
+}
@@ -1,18 +1,87 @@
 package types
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Sample represents a data sample with validation
 type Sample struct {
-	CustomerID string    `json:"customerId"`
-	Email      string    `json:"email"`
-	Name       string    `json:"name"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	CustomerID string                 `json:"customerId"`
+	Email      string                 `json:"email"`
+	Name       string                 `json:"name"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	UpdatedAt  time.Time              `json:"updatedAt"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // Extra input fields
+	Originals  map[string]string      `json:"originals,omitempty"`  // Input values changed by normalization, keyed by field
+	Position   string                 `json:"position,omitempty"`   // Location of the sample in its input, e.g. "row 3"
+	Warnings   ValidationErrors       `json:"warnings,omitempty"`   // Failed checks the sample was accepted with
+
+	// Corrections lists the values corrected automatically before the
+	// sample was validated.
+	Corrections []Correction `json:"corrections,omitempty"`
+}
+
+// Correction records a value that was corrected automatically
+type Correction struct {
+	Field     string `json:"field"`
+	Original  string `json:"original"`
+	Corrected string `json:"corrected"`
 }
 
+// Validation error codes
+const (
+	CodeRequired     = "required"               // A required field is empty
+	CodeInvalidEmail = "invalid_email"          // The email address is malformed
+	CodePattern      = "pattern"                // A field does not match a rule's pattern
+	CodeNotPattern   = "forbidden_pattern"      // A field matches a rule's forbidden pattern
+	CodeTooShort     = "too_short"              // A field is shorter than a rule's minimum length
+	CodeTooLong      = "too_long"               // A field is longer than a rule's maximum length
+	CodeNotAllowed   = "not_allowed"            // A field is not one of a rule's allowed values
+	CodeTooEarly     = "too_early"              // A timestamp is before a rule's lower bound
+	CodeTooLate      = "too_late"               // A timestamp is after a rule's upper bound
+	CodeInFuture     = "in_future"              // createdAt is further in the future than allowed
+	CodeTooOld       = "too_old"                // createdAt is older than the retention horizon
+	CodeUpdatedEarly = "updated_before_created" // updatedAt is before createdAt
+)
+
+// Email domain policy error codes
+const (
+	CodeBlockedDomain    = "blocked_domain"          // The email domain is on the global blocklist
+	CodeCustomerBlocked  = "customer_blocked_domain" // The email domain is on the customer's blocklist
+	CodeDomainNotAllowed = "domain_not_allowed"      // The email domain is not on the customer's allowlist
+	CodeDisposable       = "disposable_domain"       // The email domain belongs to a disposable email provider
+	CodeSuspiciousDomain = "suspicious_domain"       // The email domain looks like a misspelling of a known domain
+)
+
 // ValidationError represents a validation error
 type ValidationError struct {
-	CustomerID string
-	Reason     string
+	CustomerID string `json:"customerId,omitempty"`
+	Code       string `json:"code"`           // Machine-readable error code, one of the Code constants
+	Field      string `json:"field"`          // Sample field that failed validation
+	Rule       string `json:"rule,omitempty"` // Name of the failed declarative rule, if any
+	Reason     string `json:"reason"`         // Human-readable description
+
+	// Suggestion is the likely intended value, e.g. the email address with
+	// a misspelt domain corrected.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Rule != "" {
+		return fmt.Sprintf("rule %s: %s", e.Rule, e.Reason)
+	}
+	return e.Reason
+}
+
+// ValidationErrors lists every validation error found in one sample.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, len(e))
+	for i, err := range e {
+		reasons[i] = err.Error()
+	}
+	return strings.Join(reasons, "; ")
 }
@@ -0,0 +1,213 @@
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gohighlevel/pkg/types"
+
+	"golang.org/x/net/idna"
+	"gopkg.in/yaml.v3"
+)
+
+// bundledDisposableDomains is the list of disposable email providers
+// shipped with the validator.
+//
+//go:embed disposable_domains.txt
+var bundledDisposableDomains string
+
+// DomainLists configures a DomainPolicy. Domains match themselves and
+// their subdomains.
+type DomainLists struct {
+	Blocklist []string                   `json:"blocklist" yaml:"blocklist"` // Domains rejected for every customer
+	Customers map[string]CustomerDomains `json:"customers" yaml:"customers"` // Lists of individual customers, keyed by customer ID
+
+	// BlockDisposable rejects the bundled disposable email providers, plus
+	// those listed one per line in DisposableFile.
+	BlockDisposable bool   `json:"blockDisposable" yaml:"blockDisposable"`
+	DisposableFile  string `json:"disposableFile" yaml:"disposableFile"`
+}
+
+// CustomerDomains are the domain lists of a single customer. A customer's
+// allowlist takes precedence over the global blocklist and the disposable
+// providers.
+type CustomerDomains struct {
+	Allow []string `json:"allow" yaml:"allow"` // If set, only these domains are accepted
+	Block []string `json:"block" yaml:"block"` // Domains rejected for this customer
+}
+
+// DomainPolicy restricts the email domains samples may use.
+type DomainPolicy struct {
+	blocked    domainSet
+	customers  map[string]customerDomains
+	disposable domainSet
+}
+
+// customerDomains is CustomerDomains compiled into domain sets.
+type customerDomains struct {
+	allow domainSet
+	block domainSet
+}
+
+// LoadDomainPolicy reads a domain policy from a YAML (.yaml or .yml) or JSON
+// file of the form
+//
+//	blocklist: [spam.example]
+//	blockDisposable: true
+//	customers:
+//	  cust-1:
+//	    allow: [acme.com]
+//
+// A relative disposableFile is resolved against the directory of path.
+func LoadDomainPolicy(path string) (*DomainPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading domain policy: %v", err)
+	}
+
+	var lists DomainLists
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &lists)
+	default:
+		err = json.Unmarshal(data, &lists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding domain policy: %v", err)
+	}
+	if lists.DisposableFile != "" && !filepath.IsAbs(lists.DisposableFile) {
+		lists.DisposableFile = filepath.Join(filepath.Dir(path), lists.DisposableFile)
+	}
+	return NewDomainPolicy(lists)
+}
+
+// NewDomainPolicy compiles the domain lists into a policy.
+func NewDomainPolicy(lists DomainLists) (*DomainPolicy, error) {
+	p := &DomainPolicy{customers: make(map[string]customerDomains, len(lists.Customers))}
+
+	var err error
+	if p.blocked, err = newDomainSet(lists.Blocklist); err != nil {
+		return nil, fmt.Errorf("blocklist: %v", err)
+	}
+	for customerID, c := range lists.Customers {
+		var cd customerDomains
+		if cd.allow, err = newDomainSet(c.Allow); err != nil {
+			return nil, fmt.Errorf("customer %s allowlist: %v", customerID, err)
+		}
+		if cd.block, err = newDomainSet(c.Block); err != nil {
+			return nil, fmt.Errorf("customer %s blocklist: %v", customerID, err)
+		}
+		p.customers[customerID] = cd
+	}
+
+	if lists.BlockDisposable {
+		domains, err := readDomainList(strings.NewReader(bundledDisposableDomains))
+		if err != nil {
+			return nil, fmt.Errorf("bundled disposable domains: %v", err)
+		}
+		if lists.DisposableFile != "" {
+			file, err := os.Open(lists.DisposableFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading disposable domains: %v", err)
+			}
+			defer file.Close()
+
+			extra, err := readDomainList(file)
+			if err != nil {
+				return nil, fmt.Errorf("disposable domains: %v", err)
+			}
+			domains = append(domains, extra...)
+		}
+		if p.disposable, err = newDomainSet(domains); err != nil {
+			return nil, fmt.Errorf("disposable domains: %v", err)
+		}
+	}
+	return p, nil
+}
+
+// check returns the code and reason if the policy rejects the domain of
+// email for the customer, or empty strings if it accepts it.
+func (p *DomainPolicy) check(customerID, email string) (code, reason string) {
+	if p == nil {
+		return "", ""
+	}
+	domain, err := canonicalDomain(email[strings.LastIndexByte(email, '@')+1:])
+	if err != nil {
+		return "", "" // Already reported by the email check
+	}
+
+	if c, ok := p.customers[customerID]; ok {
+		switch {
+		case c.block.contains(domain):
+			return types.CodeCustomerBlocked, fmt.Sprintf("email domain %s is blocked for customer %s", domain, customerID)
+		case c.allow.contains(domain):
+			return "", ""
+		case len(c.allow) > 0:
+			return types.CodeDomainNotAllowed, fmt.Sprintf("email domain %s is not allowed for customer %s", domain, customerID)
+		}
+	}
+	switch {
+	case p.blocked.contains(domain):
+		return types.CodeBlockedDomain, fmt.Sprintf("email domain %s is blocked", domain)
+	case p.disposable.contains(domain):
+		return types.CodeDisposable, fmt.Sprintf("email domain %s is a disposable email provider", domain)
+	}
+	return "", ""
+}
+
+// domainSet is a set of domains in canonical form.
+type domainSet map[string]bool
+
+// newDomainSet builds a set from the given domains.
+func newDomainSet(domains []string) (domainSet, error) {
+	set := make(domainSet, len(domains))
+	for _, d := range domains {
+		canonical, err := canonicalDomain(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %q: %v", d, err)
+		}
+		set[canonical] = true
+	}
+	return set, nil
+}
+
+// contains reports whether domain or one of its parent domains is in the
+// set.
+func (s domainSet) contains(domain string) bool {
+	for {
+		if s[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// canonicalDomain returns the lowercase ASCII (punycode) form of domain, so
+// that Unicode and punycode spellings of a domain match.
+func canonicalDomain(domain string) (string, error) {
+	return idna.Lookup.ToASCII(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// readDomainList reads one domain per line, skipping blank lines and
+// # comments.
+func readDomainList(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			domains = append(domains, line)
+		}
+	}
+	return domains, scanner.Err()
+}
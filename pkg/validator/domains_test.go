@@ -0,0 +1,102 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/types"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	policy, err := NewDomainPolicy(DomainLists{
+		Blocklist: []string{"spam.example"},
+		Customers: map[string]CustomerDomains{
+			"acme":   {Allow: []string{"acme.com", "bücher.example"}},
+			"globex": {Block: []string{"competitor.com"}},
+			"tester": {Allow: []string{"mailinator.com"}},
+		},
+		BlockDisposable: true,
+	})
+	if err != nil {
+		t.Fatalf("NewDomainPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		customerID string
+		email      string
+		wantCode   string
+	}{
+		{"Accepted", "globex", "user@example.com", ""},
+		{"Global blocklist", "globex", "user@spam.example", types.CodeBlockedDomain},
+		{"Global blocklist subdomain", "globex", "user@mail.Spam.example", types.CodeBlockedDomain},
+		{"Customer blocklist", "globex", "user@competitor.com", types.CodeCustomerBlocked},
+		{"Customer blocklist, other customer", "acme", "user@competitor.com", types.CodeDomainNotAllowed},
+		{"Customer allowlist", "acme", "user@acme.com", ""},
+		{"Customer allowlist punycode", "acme", "user@xn--bcher-kva.example", ""},
+		{"Disposable", "globex", "user@mailinator.com", types.CodeDisposable},
+		{"Disposable, allowed for customer", "tester", "user@mailinator.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, reason := policy.check(tt.customerID, tt.email)
+			if code != tt.wantCode {
+				t.Errorf("check(%q, %q) = %q (%s), want %q", tt.customerID, tt.email, code, reason, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestLoadDomainPolicy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "disposable.txt"), []byte("# extra providers\nthrowaway.example\n"), 0644); err != nil {
+		t.Fatalf("Failed to write disposable domains: %v", err)
+	}
+	path := filepath.Join(dir, "domains.yaml")
+	content := `blocklist: [spam.example]
+blockDisposable: true
+disposableFile: disposable.txt
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write domain policy: %v", err)
+	}
+
+	policy, err := LoadDomainPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadDomainPolicy() error = %v", err)
+	}
+	for email, want := range map[string]string{
+		"user@throwaway.example": types.CodeDisposable,
+		"user@yopmail.com":       types.CodeDisposable,
+		"user@spam.example":      types.CodeBlockedDomain,
+	} {
+		if code, _ := policy.check("cust123", email); code != want {
+			t.Errorf("check(%q) = %q, want %q", email, code, want)
+		}
+	}
+}
+
+func TestValidateDomainPolicy(t *testing.T) {
+	inTempDir(t)
+
+	policy, err := NewDomainPolicy(DomainLists{BlockDisposable: true})
+	if err != nil {
+		t.Fatalf("NewDomainPolicy() error = %v", err)
+	}
+	validator := NewValidator(&mockDB{}, WithDomainPolicy(policy))
+
+	err = validator.ValidateSample(types.Sample{
+		CustomerID: "cust123",
+		Email:      "test@yopmail.com",
+		Name:       "Test User",
+		CreatedAt:  time.Now(),
+	})
+	var errs types.ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Code != types.CodeDisposable {
+		t.Errorf("ValidateSample() error = %v, want code %s", err, types.CodeDisposable)
+	}
+}
@@ -0,0 +1,216 @@
+package validator
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Length limits of an address, in octets (RFC 5321 section 4.5.3.1)
+const (
+	maxLocalLength   = 64
+	maxDomainLength  = 253
+	maxLabelLength   = 63
+	maxAddressLength = 254
+)
+
+// EmailStrictness selects which email addresses are accepted.
+type EmailStrictness int
+
+const (
+	// EmailStandard accepts dot-atom addresses (RFC 5322) with UTF-8 local
+	// parts (RFC 6531, SMTPUTF8) and internationalized domain names. The
+	// domain must have at least two labels.
+	EmailStandard EmailStrictness = iota
+
+	// EmailStrict is EmailStandard restricted to ASCII local parts, for
+	// mail systems without SMTPUTF8 support. Internationalized domains are
+	// still accepted, as they are delivered as punycode.
+	EmailStrict
+
+	// EmailLax is EmailStandard plus quoted local parts, domain literals
+	// such as [192.0.2.1], and single-label domains.
+	EmailLax
+)
+
+// ParseEmailStrictness parses "standard", "strict" or "lax".
+func ParseEmailStrictness(s string) (EmailStrictness, error) {
+	switch s {
+	case "standard":
+		return EmailStandard, nil
+	case "strict":
+		return EmailStrict, nil
+	case "lax":
+		return EmailLax, nil
+	default:
+		return 0, fmt.Errorf("unknown email strictness %q", s)
+	}
+}
+
+func (s EmailStrictness) String() string {
+	switch s {
+	case EmailStrict:
+		return "strict"
+	case EmailLax:
+		return "lax"
+	default:
+		return "standard"
+	}
+}
+
+// ValidateEmail checks address at the given strictness. The returned error
+// says why the address was rejected.
+func ValidateEmail(address string, strictness EmailStrictness) error {
+	if address == "" {
+		return fmt.Errorf("email is empty")
+	}
+	if !utf8.ValidString(address) {
+		return fmt.Errorf("email is not valid UTF-8")
+	}
+
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return fmt.Errorf("missing @")
+	}
+	local, domain := address[:at], address[at+1:]
+
+	if err := validateLocalPart(local, strictness); err != nil {
+		return err
+	}
+	asciiDomain, err := validateDomain(domain, strictness)
+	if err != nil {
+		return err
+	}
+	if len(local)+1+len(asciiDomain) > maxAddressLength {
+		return fmt.Errorf("email is longer than %d characters", maxAddressLength)
+	}
+	return nil
+}
+
+// validateLocalPart checks the part of an address before the @.
+func validateLocalPart(local string, strictness EmailStrictness) error {
+	switch {
+	case local == "":
+		return fmt.Errorf("local part is empty")
+	case len(local) > maxLocalLength:
+		return fmt.Errorf("local part is longer than %d characters", maxLocalLength)
+	case strings.HasPrefix(local, `"`):
+		if strictness != EmailLax {
+			return fmt.Errorf("quoted local parts are not allowed")
+		}
+		return validateQuotedString(local)
+	case strings.HasPrefix(local, ".") || strings.HasSuffix(local, "."):
+		return fmt.Errorf("local part starts or ends with a dot")
+	case strings.Contains(local, ".."):
+		return fmt.Errorf("local part contains consecutive dots")
+	}
+
+	for _, r := range local {
+		switch {
+		case r == '.' || isAtext(r):
+		case r >= utf8.RuneSelf && strictness == EmailStrict:
+			return fmt.Errorf("local part contains non-ASCII character %q", r)
+		case r >= utf8.RuneSelf && unicode.IsGraphic(r) && !unicode.IsSpace(r):
+		default:
+			return fmt.Errorf("local part contains invalid character %q", r)
+		}
+	}
+	return nil
+}
+
+// isAtext reports whether r may appear unquoted in a local part
+// (RFC 5322 section 3.2.3).
+func isAtext(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+}
+
+// validateQuotedString checks a quoted local part such as "john doe".
+func validateQuotedString(local string) error {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return fmt.Errorf("local part has an unterminated quote")
+	}
+	content := local[1 : len(local)-1]
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\':
+			if i++; i == len(content) {
+				return fmt.Errorf("local part ends with an escape character")
+			}
+		case c == '"':
+			return fmt.Errorf("local part contains an unescaped quote")
+		case c < ' ' || c == 0x7f:
+			return fmt.Errorf("local part contains a control character")
+		}
+	}
+	return nil
+}
+
+// validateDomain checks the part of an address after the @ and returns its
+// ASCII (punycode) form.
+func validateDomain(domain string, strictness EmailStrictness) (string, error) {
+	if domain == "" {
+		return "", fmt.Errorf("domain is empty")
+	}
+	if strings.HasPrefix(domain, "[") {
+		if strictness != EmailLax {
+			return "", fmt.Errorf("domain literals are not allowed")
+		}
+		return domain, validateDomainLiteral(domain)
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain: %v", err)
+	}
+	if len(ascii) > maxDomainLength {
+		return "", fmt.Errorf("domain is longer than %d characters", maxDomainLength)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 && strictness != EmailLax {
+		return "", fmt.Errorf("domain has no top-level domain")
+	}
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return "", fmt.Errorf("domain contains an empty label")
+		case len(label) > maxLabelLength:
+			return "", fmt.Errorf("domain label is longer than %d characters", maxLabelLength)
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			return "", fmt.Errorf("domain label %q starts or ends with a hyphen", label)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return "", fmt.Errorf("domain contains invalid character %q", r)
+			}
+		}
+	}
+	if tld := labels[len(labels)-1]; strings.Trim(tld, "0123456789") == "" {
+		return "", fmt.Errorf("top-level domain %q is numeric", tld)
+	}
+	return ascii, nil
+}
+
+// validateDomainLiteral checks a domain literal such as [192.0.2.1] or
+// [IPv6:2001:db8::1].
+func validateDomainLiteral(domain string) error {
+	if !strings.HasSuffix(domain, "]") {
+		return fmt.Errorf("domain literal is not terminated")
+	}
+	literal := domain[1 : len(domain)-1]
+	if v6, ok := strings.CutPrefix(literal, "IPv6:"); ok {
+		if addr, err := netip.ParseAddr(v6); err != nil || !addr.Is6() {
+			return fmt.Errorf("domain literal %q is not an IPv6 address", v6)
+		}
+		return nil
+	}
+	if addr, err := netip.ParseAddr(literal); err != nil || !addr.Is4() {
+		return fmt.Errorf("domain literal %q is not an IPv4 address", literal)
+	}
+	return nil
+}
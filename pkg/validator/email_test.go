@@ -0,0 +1,70 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		strictness EmailStrictness
+		wantReason string // Substring of the error, or "" for a valid address
+	}{
+		{"Valid email", "test@example.com", EmailStandard, ""},
+		{"Uppercase", "John.Doe@Example.COM", EmailStandard, ""},
+		{"Long TLD", "curator@museum.technology", EmailStandard, ""},
+		{"Plus tag", "test+tag@sub.example.co.uk", EmailStandard, ""},
+		{"IDN domain", "user@bücher.example", EmailStandard, ""},
+		{"Punycode domain", "user@xn--bcher-kva.example", EmailStandard, ""},
+		{"UTF-8 local part", "用户@例子.广告", EmailStandard, ""},
+		{"Missing @", "testexample.com", EmailStandard, "missing @"},
+		{"Empty local part", "@example.com", EmailStandard, "local part is empty"},
+		{"Leading dot", ".test@example.com", EmailStandard, "starts or ends with a dot"},
+		{"Consecutive dots", "te..st@example.com", EmailStandard, "consecutive dots"},
+		{"Space", "test @example.com", EmailStandard, "invalid character"},
+		{"Local part too long", strings.Repeat("a", 65) + "@example.com", EmailStandard, "longer than 64"},
+		{"No TLD", "test@localhost", EmailStandard, "no top-level domain"},
+		{"Numeric TLD", "test@192.168.0.1", EmailStandard, "numeric"},
+		{"Hyphen label", "test@-example.com", EmailStandard, "invalid domain"},
+		{"Underscore domain", "test@exa_mple.com", EmailStandard, "invalid domain"},
+		{"Quoted local part", `"john doe"@example.com`, EmailStandard, "quoted local parts"},
+		{"Domain literal", "test@[192.0.2.1]", EmailStandard, "domain literals"},
+		{"Strict ASCII", "test@bücher.example", EmailStrict, ""},
+		{"Strict UTF-8 local part", "josé@example.com", EmailStrict, "non-ASCII"},
+		{"Lax quoted local part", `"john doe"@example.com`, EmailLax, ""},
+		{"Lax unescaped quote", `"john"doe"@example.com`, EmailLax, "unescaped quote"},
+		{"Lax IPv4 literal", "test@[192.0.2.1]", EmailLax, ""},
+		{"Lax IPv6 literal", "test@[IPv6:2001:db8::1]", EmailLax, ""},
+		{"Lax bad literal", "test@[300.0.0.1]", EmailLax, "not an IPv4 address"},
+		{"Lax single label", "test@localhost", EmailLax, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmail(tt.email, tt.strictness)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("ValidateEmail(%q) = %v, want nil", tt.email, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("ValidateEmail(%q) = %v, want an error containing %q", tt.email, err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestParseEmailStrictness(t *testing.T) {
+	for _, s := range []EmailStrictness{EmailStandard, EmailStrict, EmailLax} {
+		got, err := ParseEmailStrictness(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseEmailStrictness(%q) = %v, %v, want %v", s.String(), got, err, s)
+		}
+	}
+	if _, err := ParseEmailStrictness("pedantic"); err == nil {
+		t.Error("ParseEmailStrictness(\"pedantic\") error = nil, want an error")
+	}
+}
@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gohighlevel/pkg/interfaces"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileConfig configures the Validator of a validation profile. Settings
+// left empty keep the options passed to LoadProfiles. Relative file paths
+// are resolved against the directory of the profiles file.
+type ProfileConfig struct {
+	Rules           string `json:"rules" yaml:"rules"`                     // Rules file, see LoadRules
+	EmailStrictness string `json:"emailStrictness" yaml:"emailStrictness"` // strict, standard or lax
+	DomainPolicy    string `json:"domainPolicy" yaml:"domainPolicy"`       // Domain policy file, see LoadDomainPolicy
+
+	// AutoCorrectEmail replaces misspelt email domains with the suggested
+	// ones, if a domain suggester is configured. Unset keeps the option
+	// passed to LoadProfiles.
+	AutoCorrectEmail *bool `json:"autoCorrectEmail" yaml:"autoCorrectEmail"`
+}
+
+// Profiles holds the validators of named validation profiles and the
+// customers assigned to them.
+type Profiles struct {
+	Validators map[string]*Validator // Validators keyed by profile name
+	Customers  map[string]string     // Profile names keyed by customer ID
+}
+
+// LoadProfiles reads validation profiles from a YAML (.yaml or .yml) or JSON
+// file of the form
+//
+//	profiles:
+//	  enterprise:
+//	    rules: enterprise-rules.yaml
+//	    emailStrictness: strict
+//	customers:
+//	  client-A: enterprise
+//
+// Each profile's validator is created with opts followed by the profile's
+// own settings.
+func LoadProfiles(path string, db interfaces.Database, opts ...Option) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profiles: %v", err)
+	}
+
+	var file struct {
+		Profiles  map[string]ProfileConfig `json:"profiles" yaml:"profiles"`
+		Customers map[string]string        `json:"customers" yaml:"customers"`
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding profiles: %v", err)
+	}
+
+	p := &Profiles{Validators: make(map[string]*Validator, len(file.Profiles)), Customers: file.Customers}
+	for name, config := range file.Profiles {
+		profileOpts, err := config.options(filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+		p.Validators[name] = NewValidator(db, append(slices.Clone(opts), profileOpts...)...)
+	}
+	for customerID, name := range p.Customers {
+		if _, ok := p.Validators[name]; !ok {
+			return nil, fmt.Errorf("customer %s: unknown profile %q", customerID, name)
+		}
+	}
+	return p, nil
+}
+
+// options returns the validator options of the profile.
+func (c ProfileConfig) options(dir string) ([]Option, error) {
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	var opts []Option
+	if c.Rules != "" {
+		rules, err := LoadRules(resolve(c.Rules))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRules(rules))
+	}
+	if c.EmailStrictness != "" {
+		strictness, err := ParseEmailStrictness(c.EmailStrictness)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithEmailStrictness(strictness))
+	}
+	if c.DomainPolicy != "" {
+		domains, err := LoadDomainPolicy(resolve(c.DomainPolicy))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithDomainPolicy(domains))
+	}
+	if c.AutoCorrectEmail != nil {
+		opts = append(opts, WithEmailAutoCorrect(*c.AutoCorrectEmail))
+	}
+	return opts, nil
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"rules.yaml": `rules:
+  - name: company-email
+    field: email
+    pattern: '@acme\.com$'
+`,
+		"profiles.yaml": `profiles:
+  enterprise:
+    rules: rules.yaml
+    emailStrictness: strict
+customers:
+  acme: enterprise
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	profiles, err := LoadProfiles(filepath.Join(dir, "profiles.yaml"), &mockDB{}, WithEmailStrictness(EmailLax))
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	if profiles.Customers["acme"] != "enterprise" {
+		t.Errorf("Expected acme to use the enterprise profile, got %q", profiles.Customers["acme"])
+	}
+	v := profiles.Validators["enterprise"]
+	if v == nil {
+		t.Fatal("Expected an enterprise validator")
+	}
+	if v.email != EmailStrict || v.rules == nil {
+		t.Errorf("Expected the profile settings to override the defaults, got strictness %v and rules %v", v.email, v.rules)
+	}
+}
+
+func TestLoadProfilesUnknownProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(`{"customers": {"acme": "enterprise"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write profiles: %v", err)
+	}
+	if _, err := LoadProfiles(path, &mockDB{}); err == nil {
+		t.Error("LoadProfiles() error = nil, want an error for an unknown profile")
+	}
+}
@@ -0,0 +1,254 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gohighlevel/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// attributePrefix selects an entry of the sample's attributes as the field
+// a rule applies to, e.g. "attributes.plan".
+const attributePrefix = "attributes."
+
+// Rule severities
+const (
+	SeverityError   = "error"   // Failing the rule rejects the sample
+	SeverityWarning = "warning" // Failing the rule only adds a warning to the sample
+)
+
+// stringFields and timeFields list the sample fields rules can refer to.
+var (
+	stringFields = []string{"customerId", "email", "name"}
+	timeFields   = []string{"createdAt", "updatedAt"}
+)
+
+// Rule is a declarative validation rule applied to one field of a sample.
+// A field that is empty (or a zero time) only fails Required; the other
+// checks apply to fields that are set.
+type Rule struct {
+	Name     string `json:"name" yaml:"name"`
+	Field    string `json:"field" yaml:"field"`                           // customerId, email, name, createdAt, updatedAt or attributes.<key>
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"` // SeverityError (the default) or SeverityWarning
+
+	Required      bool     `json:"required,omitempty" yaml:"required,omitempty"`
+	Pattern       string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`             // Regular expression the value must match
+	NotPattern    string   `json:"notPattern,omitempty" yaml:"notPattern,omitempty"`       // Regular expression the value must not match
+	MinLength     int      `json:"minLength,omitempty" yaml:"minLength,omitempty"`         // Minimum length in characters
+	MaxLength     int      `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`         // Maximum length in characters, 0 for no limit
+	AllowedValues []string `json:"allowedValues,omitempty" yaml:"allowedValues,omitempty"` // Values the field may take
+
+	// NotBefore and NotAfter bound a timestamp field. They are RFC 3339
+	// timestamps.
+	NotBefore string `json:"notBefore,omitempty" yaml:"notBefore,omitempty"`
+	NotAfter  string `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+}
+
+// RuleSet is a list of rules compiled for repeated use. A nil RuleSet has
+// no rules.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+// compiledRule is a Rule with its pattern and time bounds parsed.
+type compiledRule struct {
+	Rule
+	pattern    *regexp.Regexp
+	notPattern *regexp.Regexp
+	notBefore  time.Time
+	notAfter   time.Time
+}
+
+// LoadRules reads and compiles rules from a YAML (.yaml or .yml) or JSON
+// file of the form
+//
+//	rules:
+//	  - name: email-domain
+//	    field: email
+//	    pattern: '@example\.com$'
+//	  - name: free-mail
+//	    field: email
+//	    notPattern: '@(gmail|yahoo)\.com$'
+//	    severity: warning
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %v", err)
+	}
+
+	var file struct {
+		Rules []Rule `json:"rules" yaml:"rules"`
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding rules: %v", err)
+	}
+	return CompileRules(file.Rules)
+}
+
+// CompileRules checks the rules and compiles their patterns and time bounds.
+func CompileRules(rules []Rule) (*RuleSet, error) {
+	rs := &RuleSet{rules: make([]compiledRule, 0, len(rules))}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d: name is required", i)
+		}
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+		rs.rules = append(rs.rules, c)
+	}
+	return rs, nil
+}
+
+// compileRule checks that the checks of rule suit its field.
+func compileRule(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	switch rule.Severity {
+	case "":
+		c.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return c, fmt.Errorf("unknown severity %q", rule.Severity)
+	}
+
+	isTime := slices.Contains(timeFields, rule.Field)
+	if !isTime && !slices.Contains(stringFields, rule.Field) && !strings.HasPrefix(rule.Field, attributePrefix) {
+		return c, fmt.Errorf("unknown field %q", rule.Field)
+	}
+
+	if isTime {
+		if rule.Pattern != "" || rule.NotPattern != "" || rule.MinLength != 0 || rule.MaxLength != 0 || len(rule.AllowedValues) != 0 {
+			return c, fmt.Errorf("%s is a timestamp; only required, notBefore and notAfter apply", rule.Field)
+		}
+	} else if rule.NotBefore != "" || rule.NotAfter != "" {
+		return c, fmt.Errorf("%s is not a timestamp; notBefore and notAfter do not apply", rule.Field)
+	}
+
+	var err error
+	if rule.Pattern != "" {
+		if c.pattern, err = regexp.Compile(rule.Pattern); err != nil {
+			return c, fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	if rule.NotPattern != "" {
+		if c.notPattern, err = regexp.Compile(rule.NotPattern); err != nil {
+			return c, fmt.Errorf("invalid notPattern: %v", err)
+		}
+	}
+	if rule.MinLength < 0 || rule.MaxLength < 0 || (rule.MaxLength != 0 && rule.MaxLength < rule.MinLength) {
+		return c, fmt.Errorf("invalid length bounds %d..%d", rule.MinLength, rule.MaxLength)
+	}
+	if rule.NotBefore != "" {
+		if c.notBefore, err = time.Parse(time.RFC3339, rule.NotBefore); err != nil {
+			return c, fmt.Errorf("invalid notBefore: %v", err)
+		}
+	}
+	if rule.NotAfter != "" {
+		if c.notAfter, err = time.Parse(time.RFC3339, rule.NotAfter); err != nil {
+			return c, fmt.Errorf("invalid notAfter: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// Check applies the rules to sample in order and returns an error or
+// warning, depending on the rule's severity, for every rule it fails.
+func (rs *RuleSet) Check(sample types.Sample) (errs, warnings types.ValidationErrors) {
+	if rs == nil {
+		return nil, nil
+	}
+	for _, rule := range rs.rules {
+		code, reason := rule.check(sample)
+		if code == "" {
+			continue
+		}
+		err := types.ValidationError{
+			CustomerID: sample.CustomerID,
+			Code:       code,
+			Field:      rule.Field,
+			Rule:       rule.Name,
+			Reason:     reason,
+		}
+		if rule.Severity == SeverityWarning {
+			warnings = append(warnings, err)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	return errs, warnings
+}
+
+// check returns the code and reason of the first check of the rule that
+// sample fails, or empty strings if it passes.
+func (r *compiledRule) check(sample types.Sample) (code, reason string) {
+	if slices.Contains(timeFields, r.Field) {
+		t := sample.CreatedAt
+		if r.Field == "updatedAt" {
+			t = sample.UpdatedAt
+		}
+		switch {
+		case t.IsZero():
+			if r.Required {
+				return types.CodeRequired, r.Field + " is required"
+			}
+		case !r.notBefore.IsZero() && t.Before(r.notBefore):
+			return types.CodeTooEarly, fmt.Sprintf("%s is before %s", r.Field, r.NotBefore)
+		case !r.notAfter.IsZero() && t.After(r.notAfter):
+			return types.CodeTooLate, fmt.Sprintf("%s is after %s", r.Field, r.NotAfter)
+		}
+		return "", ""
+	}
+
+	value := fieldValue(sample, r.Field)
+	length := len([]rune(value))
+	switch {
+	case value == "":
+		if r.Required {
+			return types.CodeRequired, r.Field + " is required"
+		}
+	case r.pattern != nil && !r.pattern.MatchString(value):
+		return types.CodePattern, r.Field + " does not match the required pattern"
+	case r.notPattern != nil && r.notPattern.MatchString(value):
+		return types.CodeNotPattern, r.Field + " matches a forbidden pattern"
+	case length < r.MinLength:
+		return types.CodeTooShort, fmt.Sprintf("%s is shorter than %d characters", r.Field, r.MinLength)
+	case r.MaxLength != 0 && length > r.MaxLength:
+		return types.CodeTooLong, fmt.Sprintf("%s is longer than %d characters", r.Field, r.MaxLength)
+	case len(r.AllowedValues) != 0 && !slices.Contains(r.AllowedValues, value):
+		return types.CodeNotAllowed, fmt.Sprintf("%s must be one of %s", r.Field, strings.Join(r.AllowedValues, ", "))
+	}
+	return "", ""
+}
+
+// fieldValue returns the text of a string field or attribute of sample.
+func fieldValue(sample types.Sample, field string) string {
+	switch field {
+	case "customerId":
+		return sample.CustomerID
+	case "email":
+		return sample.Email
+	case "name":
+		return sample.Name
+	}
+
+	v, ok := sample.Attributes[strings.TrimPrefix(field, attributePrefix)]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
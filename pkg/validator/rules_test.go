@@ -0,0 +1,157 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/types"
+)
+
+func TestRuleSetCheck(t *testing.T) {
+	rules, err := CompileRules([]Rule{
+		{Name: "company-email", Field: "email", Pattern: `@example\.com$`},
+		{Name: "name-length", Field: "name", MinLength: 2, MaxLength: 10},
+		{Name: "known-plan", Field: "attributes.plan", Required: true, AllowedValues: []string{"free", "pro"}},
+		{Name: "recent", Field: "createdAt", NotBefore: "2024-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+
+	valid := types.Sample{
+		CustomerID: "cust123",
+		Email:      "test@example.com",
+		Name:       "Test User",
+		CreatedAt:  time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC),
+		Attributes: map[string]interface{}{"plan": "pro"},
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*types.Sample)
+		wantRule string
+	}{
+		{"Valid sample", func(s *types.Sample) {}, ""},
+		{"Pattern", func(s *types.Sample) { s.Email = "test@other.com" }, "company-email"},
+		{"Too short", func(s *types.Sample) { s.Name = "T" }, "name-length"},
+		{"Too long", func(s *types.Sample) { s.Name = "Test User Name" }, "name-length"},
+		{"Missing attribute", func(s *types.Sample) { s.Attributes = nil }, "known-plan"},
+		{"Not allowed", func(s *types.Sample) { s.Attributes = map[string]interface{}{"plan": "gold"} }, "known-plan"},
+		{"Too old", func(s *types.Sample) { s.CreatedAt = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }, "recent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample := valid
+			tt.modify(&sample)
+			errs, _ := rules.Check(sample)
+			if tt.wantRule == "" {
+				if errs != nil {
+					t.Errorf("Check() = %v, want nil", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Rule != tt.wantRule {
+				t.Errorf("Check() = %v, want rule %s", errs, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestCompileRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"Missing name", Rule{Field: "email", Required: true}},
+		{"Unknown field", Rule{Name: "r", Field: "phone", Required: true}},
+		{"Bad pattern", Rule{Name: "r", Field: "email", Pattern: "("}},
+		{"Bad length bounds", Rule{Name: "r", Field: "name", MinLength: 5, MaxLength: 2}},
+		{"Pattern on a timestamp", Rule{Name: "r", Field: "createdAt", Pattern: "2024"}},
+		{"Time bound on a string", Rule{Name: "r", Field: "name", NotAfter: "2024-01-01T00:00:00Z"}},
+		{"Bad time bound", Rule{Name: "r", Field: "createdAt", NotAfter: "2024-01-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CompileRules([]Rule{tt.rule}); err == nil {
+				t.Errorf("CompileRules() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadRulesYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `rules:
+  - name: known-plan
+    field: attributes.plan
+    allowedValues: [free, pro]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	errs, _ := rules.Check(types.Sample{Attributes: map[string]interface{}{"plan": "gold"}})
+	if len(errs) != 1 || errs[0].Rule != "known-plan" || errs[0].Code != types.CodeNotAllowed {
+		t.Errorf("Check() = %v, want rule known-plan", errs)
+	}
+}
+
+func TestValidateSampleRules(t *testing.T) {
+	inTempDir(t)
+
+	rules, err := CompileRules([]Rule{{Name: "company-email", Field: "email", Pattern: `@example\.com$`}})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+	validator := NewValidator(&mockDB{}, WithRules(rules))
+
+	err = validator.ValidateSample(types.Sample{
+		CustomerID: "cust123",
+		Email:      "test@other.com",
+		Name:       "Test User",
+		CreatedAt:  time.Now(),
+	})
+	if err == nil {
+		t.Fatal("ValidateSample() error = nil, want a rule error")
+	}
+
+	data, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(data), `"rule": "company-email"`) {
+		t.Errorf("Expected the rule name in error.log, got:\n%s", data)
+	}
+}
+
+func TestRuleSetCheckWarnings(t *testing.T) {
+	rules, err := CompileRules([]Rule{
+		{Name: "all-caps", Field: "name", Pattern: `\p{Ll}`, Severity: SeverityWarning},
+		{Name: "free-mail", Field: "email", NotPattern: `@(gmail|yahoo)\.com$`, Severity: SeverityWarning},
+		{Name: "name-length", Field: "name", MaxLength: 10},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules() error = %v", err)
+	}
+
+	errs, warnings := rules.Check(types.Sample{Name: "JOHN DOE SMITH", Email: "john@gmail.com"})
+	if len(errs) != 1 || errs[0].Rule != "name-length" {
+		t.Errorf("Check() errors = %v, want rule name-length", errs)
+	}
+	if len(warnings) != 2 || warnings[0].Rule != "all-caps" || warnings[1].Code != types.CodeNotPattern {
+		t.Errorf("Check() warnings = %v, want rules all-caps and free-mail", warnings)
+	}
+
+	if _, err := CompileRules([]Rule{{Name: "r", Field: "name", Required: true, Severity: "fatal"}}); err == nil {
+		t.Error("CompileRules() error = nil, want an error for an unknown severity")
+	}
+}
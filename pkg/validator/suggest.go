@@ -0,0 +1,159 @@
+package validator
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// bundledKnownDomains is the list of popular email domains shipped with
+// the validator.
+//
+//go:embed known_domains.txt
+var bundledKnownDomains string
+
+// DomainSuggester suggests corrections for misspelt email domains, such as
+// gmial.com for gmail.com, by edit distance against a list of known
+// domains. Regional variants of a known provider, such as hotmail.de for
+// hotmail.com, are not misspellings.
+type DomainSuggester struct {
+	known  []string
+	set    map[string]bool
+	labels map[string]bool // Registrable labels of the known domains, such as hotmail
+}
+
+// NewDomainSuggester creates a suggester matching against the bundled
+// known domains plus the given ones.
+func NewDomainSuggester(extra ...string) *DomainSuggester {
+	domains, _ := readDomainList(strings.NewReader(bundledKnownDomains))
+	s := &DomainSuggester{set: make(map[string]bool), labels: make(map[string]bool)}
+	for _, d := range append(domains, extra...) {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" && !s.set[d] {
+			s.set[d] = true
+			s.known = append(s.known, d)
+			if label := registrableLabel(d); label != "" {
+				s.labels[label] = true
+			}
+		}
+	}
+	return s
+}
+
+// LoadDomainSuggester creates a suggester matching against the bundled
+// known domains plus those listed one per line in the file at path.
+func LoadDomainSuggester(path string) (*DomainSuggester, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading known domains: %v", err)
+	}
+	defer file.Close()
+
+	extra, err := readDomainList(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading known domains: %v", err)
+	}
+	return NewDomainSuggester(extra...), nil
+}
+
+// Suggest returns the known domain that domain is most likely a
+// misspelling of. It reports false for known domains, for domains that
+// only differ from a known one in their public suffix, such as
+// yahoo.co.jp for yahoo.co.in, and for domains not close to any known one.
+func (s *DomainSuggester) Suggest(domain string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	domain = strings.ToLower(domain)
+	if domain == "" || s.set[domain] {
+		return "", false
+	}
+	if label := registrableLabel(domain); label != "" && s.labels[label] {
+		return "", false
+	}
+
+	best, bestDistance := "", maxTypoDistance(domain)+1
+	for _, known := range s.known {
+		if d := editDistance(domain, known); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best, best != ""
+}
+
+// SuggestEmail returns email with its domain replaced by the suggested
+// correction, if there is one.
+func (s *DomainSuggester) SuggestEmail(email string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return "", false
+	}
+	domain, ok := s.Suggest(email[at+1:])
+	if !ok {
+		return "", false
+	}
+	return email[:at+1] + domain, true
+}
+
+// registrableLabel returns the label of domain just below its public
+// suffix, such as hotmail for hotmail.co.uk, or "" if domain does not end
+// in an ICANN public suffix.
+func registrableLabel(domain string) string {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	if !icann || len(domain) <= len(suffix)+1 {
+		return ""
+	}
+	rest := domain[:len(domain)-len(suffix)-1]
+	return rest[strings.LastIndexByte(rest, '.')+1:]
+}
+
+// maxTypoDistance is the largest edit distance at which domain is still
+// considered a misspelling. Short domains allow fewer edits, and domains
+// of up to six characters, such as mx.com, are too short to tell apart
+// from known ones like me.com.
+func maxTypoDistance(domain string) int {
+	switch n := len(domain); {
+	case n <= 6:
+		return 0
+	case n <= 9:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a
+// and b: the number of single-character insertions, deletions,
+// substitutions and adjacent transpositions needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rows of the distance matrix: two rows back, previous and current
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,183 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/types"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"gmail.com", "gmail.com", 0},
+		{"gmial.com", "gmail.com", 1},  // Transposition
+		{"gmai.com", "gmail.com", 1},   // Deletion
+		{"gmaill.com", "gmail.com", 1}, // Insertion
+		{"gmail.con", "gmail.com", 1},  // Substitution
+		{"hotmial.con", "hotmail.com", 2},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDomainSuggester(t *testing.T) {
+	s := NewDomainSuggester("client-a.com")
+	tests := []struct {
+		domain string
+		want   string // Suggested domain, or "" for none
+	}{
+		{"gmial.com", "gmail.com"},
+		{"GMIAL.COM", "gmail.com"},
+		{"yaho.com", "yahoo.com"},
+		{"hotmial.con", "hotmail.com"},
+		{"outlok.com", "outlook.com"},
+		{"clinet-a.com", "client-a.com"},
+		{"gmail.com", ""},
+		{"example.com", ""},
+		{"mx.com", ""}, // Too short to tell apart from me.com
+
+		// Real providers that are close to others are left alone
+		{"hotmail.de", ""},
+		{"hotmail.it", ""},
+		{"hotmail.es", ""},
+		{"yahoo.co.jp", ""},
+		{"email.com", ""},
+		{"hotmail.com.ar", ""}, // Regional variant not listed
+		{"gmail.con", "gmail.com"},
+	}
+
+	for _, tt := range tests {
+		got, ok := s.Suggest(tt.domain)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Suggest(%q) = %q, %v, want %q", tt.domain, got, ok, tt.want)
+		}
+	}
+
+	if got, ok := s.SuggestEmail("Jane.Doe@gmial.com"); got != "Jane.Doe@gmail.com" || !ok {
+		t.Errorf("SuggestEmail() = %q, %v, want %q", got, ok, "Jane.Doe@gmail.com")
+	}
+	var none *DomainSuggester
+	if _, ok := none.SuggestEmail("jane@gmial.com"); ok {
+		t.Error("SuggestEmail() on a nil suggester reported a suggestion")
+	}
+}
+
+func TestValidateSuggestsDomain(t *testing.T) {
+	inTempDir(t)
+
+	validator := NewValidator(&mockDB{}, WithDomainSuggester(NewDomainSuggester()))
+	sample := types.Sample{CustomerID: "cust123", Name: "Jane", CreatedAt: time.Now()}
+
+	// Valid emails with a misspelt domain are accepted with a warning
+	sample.Email = "jane@gmial.com"
+	warnings, err := validator.Validate(sample)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != types.CodeSuspiciousDomain || warnings[0].Suggestion != "jane@gmail.com" {
+		t.Errorf("Validate() warnings = %+v, want a %s warning suggesting jane@gmail.com", warnings, types.CodeSuspiciousDomain)
+	}
+
+	// Invalid emails carry the suggestion in the error log
+	sample.Email = "jane@gmail,com"
+	_, err = validator.Validate(sample)
+	var errs types.ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Suggestion != "jane@gmail.com" {
+		t.Errorf("Validate() error = %+v, want a suggestion of jane@gmail.com", err)
+	}
+	data, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	if !strings.Contains(string(data), `"suggestion": "jane@gmail.com"`) {
+		t.Errorf("error.log does not contain the suggestion:\n%s", data)
+	}
+}
+
+func TestCorrectSample(t *testing.T) {
+	sample := types.Sample{CustomerID: "cust123", Email: "jane@gmial.com"}
+	unresolved := WithDomainResolver(func(string) bool { return false })
+
+	if got := NewValidator(&mockDB{}, WithDomainSuggester(NewDomainSuggester()), unresolved).CorrectSample(sample); got.Email != sample.Email {
+		t.Errorf("CorrectSample() without auto-correction changed the email to %q", got.Email)
+	}
+
+	validator := NewValidator(&mockDB{}, WithDomainSuggester(NewDomainSuggester()), WithEmailAutoCorrect(true), unresolved)
+	got := validator.CorrectSample(sample)
+	want := types.Correction{Field: "email", Original: "jane@gmial.com", Corrected: "jane@gmail.com"}
+	if got.Email != want.Corrected || len(got.Corrections) != 1 || got.Corrections[0] != want {
+		t.Errorf("CorrectSample() = %q with corrections %+v, want %q with %+v", got.Email, got.Corrections, want.Corrected, want)
+	}
+	if got := validator.CorrectSample(got); len(got.Corrections) != 1 {
+		t.Errorf("CorrectSample() corrected an already correct email: %+v", got.Corrections)
+	}
+}
+
+func TestCorrectSampleKeepsResolvingDomains(t *testing.T) {
+	var looked []string
+	validator := NewValidator(&mockDB{},
+		WithDomainSuggester(NewDomainSuggester()),
+		WithEmailAutoCorrect(true),
+		WithDomainResolver(func(domain string) bool {
+			looked = append(looked, domain)
+			return true
+		}),
+	)
+
+	// Valid emails whose domain resolves are not rewritten
+	for _, email := range []string{"jane@gmial.com", "jane@hotmail.de", "jane@yahoo.co.jp", "jane@email.com"} {
+		if got := validator.CorrectSample(types.Sample{Email: email}); got.Email != email || len(got.Corrections) != 0 {
+			t.Errorf("CorrectSample(%q) = %q with corrections %+v, want it unchanged", email, got.Email, got.Corrections)
+		}
+	}
+	if len(looked) != 1 || looked[0] != "gmial.com" {
+		t.Errorf("Expected only the suggested domain to be looked up, looked up %v", looked)
+	}
+
+	// Invalid emails are corrected whether or not their domain resolves
+	if got := validator.CorrectSample(types.Sample{Email: "jane..doe@gmial.com"}); got.Email != "jane..doe@gmail.com" {
+		t.Errorf("CorrectSample() = %q, want the invalid email corrected", got.Email)
+	}
+}
+
+func TestCorrectSampleCachesResolution(t *testing.T) {
+	lookups := 0
+	now := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+	validator := NewValidator(&mockDB{},
+		WithDomainSuggester(NewDomainSuggester()),
+		WithEmailAutoCorrect(true),
+		WithDomainResolver(func(string) bool {
+			lookups++
+			return false
+		}),
+	)
+	validator.now = func() time.Time { return now }
+
+	// Samples sharing a domain look it up once
+	for _, email := range []string{"jane@gmial.com", "john@GMIAL.com", "joe@gmial.com"} {
+		if got := validator.CorrectSample(types.Sample{Email: email}); len(got.Corrections) != 1 {
+			t.Errorf("CorrectSample(%q) = %q, want it corrected", email, got.Email)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", lookups)
+	}
+
+	// The answer is looked up again once it expires
+	now = now.Add(resolveCacheTTL)
+	validator.CorrectSample(types.Sample{Email: "jane@gmial.com"})
+	if lookups != 2 {
+		t.Errorf("Expected an expired answer to be looked up again, got %d lookups", lookups)
+	}
+}
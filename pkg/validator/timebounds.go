@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"fmt"
+	"time"
+
+	"gohighlevel/pkg/types"
+)
+
+// TimeBounds limits how far a sample's createdAt may be from the time it is
+// validated. A zero limit is not enforced.
+type TimeBounds struct {
+	MaxFutureSkew time.Duration // How far in the future createdAt may be
+	MaxAge        time.Duration // Retention horizon: how far in the past createdAt may be
+
+	// Flag accepts samples outside the bounds with a warning instead of
+	// rejecting them.
+	Flag bool
+}
+
+// check returns the code and reason if createdAt is outside the bounds at
+// now, or empty strings if it is within them.
+func (b TimeBounds) check(createdAt, now time.Time) (code, reason string) {
+	switch {
+	case b.MaxFutureSkew > 0 && createdAt.After(now.Add(b.MaxFutureSkew)):
+		return types.CodeInFuture, fmt.Sprintf("createdAt is more than %v in the future", b.MaxFutureSkew)
+	case b.MaxAge > 0 && createdAt.Before(now.Add(-b.MaxAge)):
+		return types.CodeTooOld, fmt.Sprintf("createdAt is older than %v", b.MaxAge)
+	}
+	return "", ""
+}
@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gohighlevel/pkg/types"
+)
+
+func TestValidateTimeBounds(t *testing.T) {
+	inTempDir(t)
+
+	now := time.Date(2024, 3, 26, 12, 0, 0, 0, time.UTC)
+	bounds := TimeBounds{MaxFutureSkew: time.Hour, MaxAge: 365 * 24 * time.Hour}
+
+	tests := []struct {
+		name        string
+		flag        bool
+		createdAt   time.Time
+		updatedAt   time.Time
+		wantCode    string // Code of the rejection, if any
+		wantWarning string // Code of the warning, if any
+	}{
+		{"Within bounds", false, now.Add(-time.Hour), time.Time{}, "", ""},
+		{"Small future skew", false, now.Add(30 * time.Minute), time.Time{}, "", ""},
+		{"Far future", false, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}, types.CodeInFuture, ""},
+		{"Epoch", false, time.Unix(0, 0), time.Time{}, types.CodeTooOld, ""},
+		{"Flagged future", true, now.Add(2 * time.Hour), time.Time{}, "", types.CodeInFuture},
+		{"Flagged old", true, time.Unix(0, 0), time.Time{}, "", types.CodeTooOld},
+		{"Updated after created", false, now, now.Add(time.Minute), "", ""},
+		{"Updated before created", false, now, now.Add(-time.Minute), types.CodeUpdatedEarly, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bounds
+			b.Flag = tt.flag
+			validator := NewValidator(&mockDB{}, WithTimeBounds(b))
+			validator.now = func() time.Time { return now }
+
+			warnings, err := validator.Validate(types.Sample{
+				CustomerID: "cust123",
+				Email:      "test@example.com",
+				Name:       "Test User",
+				CreatedAt:  tt.createdAt,
+				UpdatedAt:  tt.updatedAt,
+			})
+
+			var errs types.ValidationErrors
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Errorf("Validate() error = %v, want nil", err)
+			case tt.wantCode != "" && (!errors.As(err, &errs) || errs[0].Code != tt.wantCode):
+				t.Errorf("Validate() error = %v, want code %s", err, tt.wantCode)
+			}
+			switch {
+			case tt.wantWarning == "" && warnings != nil:
+				t.Errorf("Validate() warnings = %v, want nil", warnings)
+			case tt.wantWarning != "" && (len(warnings) != 1 || warnings[0].Code != tt.wantWarning):
+				t.Errorf("Validate() warnings = %v, want code %s", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -1,67 +1,314 @@
 package validator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
-	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"gohighlevel/pkg/interfaces"
 	"gohighlevel/pkg/types"
+
+	"golang.org/x/net/idna"
 )
 
 // Validator handles the validation of sample data and error logging.
 // It maintains a count of validation errors and provides methods to
-// validate samples and log errors.
+// validate samples and log errors. It is safe for concurrent use.
 type Validator struct {
 	db         interfaces.Database
-	errorCount int // Tracks the number of validation errors encountered
+	rules      *RuleSet         // Declarative rules applied after the built-in checks
+	email      EmailStrictness  // Which email addresses are accepted
+	domains    *DomainPolicy    // Restricts the email domains samples may use
+	suggester  *DomainSuggester // Suggests corrections for misspelt email domains
+	correct    bool             // Whether CorrectSample applies the suggestions
+	timeBounds TimeBounds       // Limits on createdAt relative to the current time
+	now        func() time.Time // Current time, replaceable in tests
+	mu         sync.Mutex       // Serializes writes to the error and warnings logs
+	errorCount int              // Tracks the number of validation errors encountered
+	warnCount  int              // Tracks the number of samples accepted with warnings
+
+	// resolves reports whether an email domain can receive mail, so that
+	// CorrectSample leaves real domains alone
+	resolves func(domain string) bool
+
+	// resolved caches the answers of resolves, so that the samples sharing
+	// a domain look it up once
+	resolved resolveCache
+}
+
+const (
+	resolveCacheTTL  = time.Hour // How long a domain's resolution is reused
+	resolveCacheSize = 10000     // Maximum number of cached domains
+)
+
+// resolveCache remembers whether email domains resolve, for up to
+// resolveCacheTTL and resolveCacheSize domains.
+type resolveCache struct {
+	mu      sync.Mutex
+	entries map[string]resolveEntry
+}
+
+type resolveEntry struct {
+	resolves bool
+	expires  time.Time
+}
+
+// get returns the cached resolution of domain, if any is current at now.
+func (c *resolveCache) get(domain string, now time.Time) (resolves, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[domain]
+	if !ok || !now.Before(e.expires) {
+		return false, false
+	}
+	return e.resolves, true
+}
+
+// put caches the resolution of domain at now. When the cache is full the
+// expired entries are dropped, and if none are, every entry is.
+func (c *resolveCache) put(domain string, resolves bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]resolveEntry)
+	}
+	if len(c.entries) >= resolveCacheSize {
+		for d, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, d)
+			}
+		}
+		if len(c.entries) >= resolveCacheSize {
+			clear(c.entries)
+		}
+	}
+	c.entries[domain] = resolveEntry{resolves: resolves, expires: now.Add(resolveCacheTTL)}
+}
+
+// Option configures optional behaviour of a Validator.
+type Option func(*Validator)
+
+// WithRules applies the given rules to every sample after the built-in
+// checks.
+func WithRules(rules *RuleSet) Option {
+	return func(v *Validator) {
+		v.rules = rules
+	}
+}
+
+// WithEmailStrictness sets which email addresses are accepted. The default
+// is EmailStandard.
+func WithEmailStrictness(strictness EmailStrictness) Option {
+	return func(v *Validator) {
+		v.email = strictness
+	}
+}
+
+// WithDomainPolicy rejects samples whose email domain the policy does not
+// accept.
+func WithDomainPolicy(p *DomainPolicy) Option {
+	return func(v *Validator) {
+		v.domains = p
+	}
+}
+
+// WithDomainSuggester suggests corrections for misspelt email domains.
+// Suggestions are included in the error log for invalid emails, and
+// valid emails with a suspicious domain are accepted with a warning.
+func WithDomainSuggester(s *DomainSuggester) Option {
+	return func(v *Validator) {
+		v.suggester = s
+	}
+}
+
+// WithEmailAutoCorrect makes CorrectSample replace misspelt email domains
+// with the suggested ones. It requires WithDomainSuggester. Only invalid
+// emails and emails whose domain does not resolve are corrected.
+func WithEmailAutoCorrect(enabled bool) Option {
+	return func(v *Validator) {
+		v.correct = enabled
+	}
+}
+
+// WithDomainResolver sets how CorrectSample checks whether an email domain
+// can receive mail. The default looks up its MX, or failing that address,
+// records, and treats lookups that fail for reasons other than the domain
+// not existing as resolving. Answers are cached per domain for an hour.
+func WithDomainResolver(resolves func(domain string) bool) Option {
+	return func(v *Validator) {
+		v.resolves = resolves
+	}
+}
+
+// WithTimeBounds limits how far createdAt may be from the current time.
+func WithTimeBounds(bounds TimeBounds) Option {
+	return func(v *Validator) {
+		v.timeBounds = bounds
+	}
 }
 
 // NewValidator creates a new validator instance with the given database connection.
-func NewValidator(db interfaces.Database) *Validator {
-	return &Validator{
+func NewValidator(db interfaces.Database, opts ...Option) *Validator {
+	v := &Validator{
 		db:         db,
 		errorCount: 0,
+		resolves:   resolvesMail,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
 	}
+	return v
 }
 
-// ValidateSample performs validation checks on a sample:
+// ValidateSample validates a sample like Validate, ignoring any warnings.
+func (v *Validator) ValidateSample(sample types.Sample) error {
+	_, err := v.Validate(sample)
+	return err
+}
+
+// Validate performs validation checks on a sample:
 // 1. Checks if customer ID is present
-// 2. Validates email format
+// 2. Validates email format, checks the domain policy and looks for
+// misspelt domains
 // 3. Ensures name is not empty
-// 4. Verifies timestamp is valid
-// Returns error if any validation fails, nil otherwise.
-func (v *Validator) ValidateSample(sample types.Sample) error {
+// 4. Verifies timestamp is valid, within the time bounds, and not after
+// updatedAt
+// 5. Applies the configured rules
+// Every failed check is collected, and the failures are logged as a single
+// entry and returned as types.ValidationErrors. If all checks pass, the
+// checks the sample was flagged by are returned as warnings.
+func (v *Validator) Validate(sample types.Sample) (types.ValidationErrors, error) {
+	var errs, warnings types.ValidationErrors
+	fail := func(code, field, reason string) {
+		errs = append(errs, types.ValidationError{CustomerID: sample.CustomerID, Code: code, Field: field, Reason: reason})
+	}
+
 	// Validate customer ID
 	if sample.CustomerID == "" {
-		v.writeErrorLog(sample.CustomerID, "customer_id is required")
-		return fmt.Errorf("customer_id is required")
+		fail(types.CodeRequired, "customerId", "customer_id is required")
 	}
 
 	// Validate email
-	if !isValidEmail(sample.Email) {
-		v.writeErrorLog(sample.CustomerID, "invalid email format")
-		return fmt.Errorf("invalid email format")
+	if err := ValidateEmail(sample.Email, v.email); err != nil {
+		fail(types.CodeInvalidEmail, "email", "invalid email format: "+err.Error())
+		errs[len(errs)-1].Suggestion, _ = v.suggester.SuggestEmail(sample.Email)
+	} else if code, reason := v.domains.check(sample.CustomerID, sample.Email); code != "" {
+		fail(code, "email", reason)
+	} else if suggestion, ok := v.suggester.SuggestEmail(sample.Email); ok {
+		warnings = append(warnings, types.ValidationError{
+			CustomerID: sample.CustomerID,
+			Code:       types.CodeSuspiciousDomain,
+			Field:      "email",
+			Reason:     "email domain looks like a misspelling",
+			Suggestion: suggestion,
+		})
 	}
 
 	// Validate name
 	if sample.Name == "" {
-		v.writeErrorLog(sample.CustomerID, "name is required")
-		return fmt.Errorf("name is required")
+		fail(types.CodeRequired, "name", "name is required")
 	}
 
 	// Validate timestamps
 	if sample.CreatedAt.IsZero() {
-		v.writeErrorLog(sample.CustomerID, "created_at is required")
-		return fmt.Errorf("created_at is required")
+		fail(types.CodeRequired, "createdAt", "created_at is required")
+	} else if code, reason := v.timeBounds.check(sample.CreatedAt, v.now()); code != "" {
+		if v.timeBounds.Flag {
+			warnings = append(warnings, types.ValidationError{CustomerID: sample.CustomerID, Code: code, Field: "createdAt", Reason: reason})
+		} else {
+			fail(code, "createdAt", reason)
+		}
+	}
+	if !sample.CreatedAt.IsZero() && !sample.UpdatedAt.IsZero() && sample.UpdatedAt.Before(sample.CreatedAt) {
+		fail(types.CodeUpdatedEarly, "updatedAt", "updatedAt is before createdAt")
+	}
+
+	// Apply the declarative rules
+	ruleErrs, ruleWarnings := v.rules.Check(sample)
+	errs = append(errs, ruleErrs...)
+	warnings = append(warnings, ruleWarnings...)
+
+	if len(errs) > 0 {
+		v.writeEntry(errorEntry{CustomerID: sample.CustomerID, Position: sample.Position, Reason: errs.Error(), Errors: errs, Originals: sample.Originals})
+		return nil, errs
 	}
 
 	if sample.UpdatedAt.IsZero() {
 		sample.UpdatedAt = time.Now()
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// CorrectSample replaces a misspelt email domain with the suggested one
+// if auto-correction is enabled, recording the change in the sample's
+// corrections. Valid emails whose domain resolves are left alone, as they
+// may well be real.
+func (v *Validator) CorrectSample(sample types.Sample) types.Sample {
+	if !v.correct {
+		return sample
+	}
+	corrected, ok := v.suggester.SuggestEmail(sample.Email)
+	if !ok {
+		return sample
+	}
+	domain := sample.Email[strings.LastIndexByte(sample.Email, '@')+1:]
+	if ValidateEmail(sample.Email, v.email) == nil && v.domainResolves(domain) {
+		return sample
+	}
+
+	sample.Corrections = append(slices.Clip(sample.Corrections), types.Correction{
+		Field:     "email",
+		Original:  sample.Email,
+		Corrected: corrected,
+	})
+	sample.Email = corrected
+	return sample
+}
+
+// domainResolves reports whether domain can receive mail, using the cached
+// answer if there is a current one.
+func (v *Validator) domainResolves(domain string) bool {
+	domain = strings.ToLower(domain)
+	now := v.now()
+	if resolves, ok := v.resolved.get(domain, now); ok {
+		return resolves
+	}
+	resolves := v.resolves(domain)
+	v.resolved.put(domain, resolves, now)
+	return resolves
+}
+
+// resolvesMail reports whether domain has MX or address records. Only a
+// lookup answering that the domain does not exist counts as not resolving,
+// so that a DNS outage does not rewrite valid emails.
+func resolvesMail(domain string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+	if _, err := net.DefaultResolver.LookupMX(ctx, domain); !isNotFound(err) {
+		return true
+	}
+	_, err := net.DefaultResolver.LookupHost(ctx, domain)
+	return !isNotFound(err)
+}
+
+// isNotFound reports whether err is a DNS answer that a name does not
+// exist or has no records of the requested type.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
 }
 
 // WriteErrorLog is a public method to write errors to the log file.
@@ -70,47 +317,102 @@ func (v *Validator) WriteErrorLog(customerID, reason string) error {
 	return v.writeErrorLog(customerID, reason)
 }
 
-// writeErrorLog writes an error entry to the error.log file and increments the error counter.
-// Each error entry includes:
-// - Status (always "error")
+// WriteSampleErrorLog writes an error for sample to the log file, including
+// the input values that normalization changed.
+func (v *Validator) WriteSampleErrorLog(sample types.Sample, reason string) error {
+	return v.writeEntry(errorEntry{CustomerID: sample.CustomerID, Position: sample.Position, Reason: reason, Originals: sample.Originals})
+}
+
+// WriteWarningLog writes the warnings a sample was accepted with to the
+// warnings.log file.
+func (v *Validator) WriteWarningLog(sample types.Sample) error {
+	entry := errorEntry{
+		Status:     "warning",
+		CustomerID: sample.CustomerID,
+		Position:   sample.Position,
+		Reason:     sample.Warnings.Error(),
+		Warnings:   sample.Warnings,
+		Originals:  sample.Originals,
+	}
+	return v.appendLog("warnings.log", entry, &v.warnCount)
+}
+
+// errorEntry is an entry of the error or warnings log. Each entry includes:
+// - Status ("error" or "warning")
 // - Customer ID
+// - Location of the sample in its input, if known
 // - Error reason
+// - Every validation error, for samples that failed validation
+// - Every warning, for samples accepted with warnings
+// - Input values changed by normalization, if any
 // - Timestamp
+type errorEntry struct {
+	Status     string                 `json:"status"`
+	CustomerID string                 `json:"customerId"`
+	Position   string                 `json:"position,omitempty"`
+	Reason     string                 `json:"reason"`
+	Errors     types.ValidationErrors `json:"errors,omitempty"`
+	Warnings   types.ValidationErrors `json:"warnings,omitempty"`
+	Originals  map[string]string      `json:"originals,omitempty"`
+	CreatedAt  string                 `json:"createdAt"`
+}
+
+// writeErrorLog writes an error entry to the error.log file and increments the error counter.
 func (v *Validator) writeErrorLog(customerID, reason string) error {
-	file, err := os.OpenFile("error.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return v.writeEntry(errorEntry{CustomerID: customerID, Reason: reason})
+}
+
+// writeEntry appends an error entry to the error.log file and increments
+// the error counter.
+func (v *Validator) writeEntry(entry errorEntry) error {
+	entry.Status = "error"
+	return v.appendLog("error.log", entry, &v.errorCount)
+}
+
+// appendLog completes and appends entry to the log file at path, and
+// increments count.
+func (v *Validator) appendLog(path string, entry errorEntry, count *int) error {
+	entry.CreatedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(entry, "", "\t")
 	if err != nil {
-		return fmt.Errorf("failed to open error log: %v", err)
+		return fmt.Errorf("failed to encode %s entry: %v", path, err)
 	}
-	defer file.Close()
 
-	errorLog := fmt.Sprintf(`{
-	"status": "error",
-	"customerId": "%s",
-	"reason": "%s",
-	"createdAt": "%s"
-}
-`, customerID, reason, time.Now().Format(time.RFC3339))
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
 
-	_, err = file.WriteString(errorLog)
+	_, err = file.Write(append(data, '\n'))
 	if err != nil {
-		return fmt.Errorf("failed to write to error log: %v", err)
+		return fmt.Errorf("failed to write to %s: %v", path, err)
 	}
 
-	v.errorCount++
+	*count++
 	return nil
 }
 
 // GetErrorCount returns the total number of validation errors encountered.
 func (v *Validator) GetErrorCount() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	return v.errorCount
 }
 
-// isValidEmail checks if the email string matches a valid email format.
-// The regex pattern ensures:
-// - Local part contains letters, numbers, and common special characters
-// - Domain part has a valid structure with TLD
-// - TLD is 2-4 characters long
+// isValidEmail checks if the email string is a valid address at the
+// default EmailStandard strictness.
 func isValidEmail(email string) bool {
-	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-	return emailRegex.MatchString(email)
+	return ValidateEmail(email, EmailStandard) == nil
+}
+
+// GetWarningCount returns the total number of samples logged as accepted
+// with warnings.
+func (v *Validator) GetWarningCount() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.warnCount
 }
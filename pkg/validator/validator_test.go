@@ -1,6 +1,10 @@
 package validator
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -13,6 +17,23 @@ func (m *mockDB) Init() error                            { return nil }
 func (m *mockDB) Close()                                 {}
 func (m *mockDB) InsertSample(sample types.Sample) error { return nil }
 
+// inTempDir runs the rest of the test in a temporary directory, so that the
+// logs it writes start empty and the checked-in error.log is left alone.
+func inTempDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("Failed to restore working directory: %v", err)
+		}
+	})
+}
+
 func TestValidateSample(t *testing.T) {
 	validator := NewValidator(&mockDB{})
 
@@ -120,3 +141,45 @@ func BenchmarkIsValidEmail(b *testing.B) {
 		_ = isValidEmail(email)
 	}
 }
+
+func TestValidateSampleCollectsAllErrors(t *testing.T) {
+	inTempDir(t)
+
+	validator := NewValidator(&mockDB{})
+	err := validator.ValidateSample(types.Sample{
+		CustomerID: "cust123",
+		Email:      "invalid-email",
+		CreatedAt:  time.Now(),
+	})
+
+	var errs types.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ValidateSample() error = %v, want types.ValidationErrors", err)
+	}
+	var codes []string
+	for _, e := range errs {
+		codes = append(codes, e.Field+":"+e.Code)
+	}
+	if want := []string{"email:invalid_email", "name:required"}; !slices.Equal(codes, want) {
+		t.Errorf("ValidateSample() errors = %v, want %v", codes, want)
+	}
+
+	// The record is logged and counted once, with every error listed
+	if got := validator.GetErrorCount(); got != 1 {
+		t.Errorf("GetErrorCount() = %d, want 1", got)
+	}
+	data, err := os.ReadFile("error.log")
+	if err != nil {
+		t.Fatalf("Failed to read error.log: %v", err)
+	}
+	var entry struct {
+		CustomerID string                  `json:"customerId"`
+		Errors     []types.ValidationError `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Failed to decode error.log entry: %v", err)
+	}
+	if entry.CustomerID != "cust123" || len(entry.Errors) != 2 {
+		t.Errorf("Unexpected error.log entry:\n%s", data)
+	}
+}